@@ -0,0 +1,64 @@
+package main
+
+import (
+	"brubot/internal/domain"
+	"brubot/internal/target"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+// printChanges prints a table of planned submissions for a target account against its current tips
+func printChanges(name string, changes []target.Change) {
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(w, "Target: %s\n", name)
+	fmt.Fprintln(w, "FIXTURE\tCURRENT\tNEW\tCHANGE\tREQUEST")
+	for _, c := range changes {
+		current := "-"
+		if c.Current != nil {
+			current = tip(*c.Current)
+		}
+		planned, request := tip(c.Prediction), c.Request
+		if c.Missing {
+			planned, request = "-", "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", c.Prediction.Fixture, current, planned, c.Summary(), request)
+	}
+	fmt.Fprintln(w)
+
+	w.Flush()
+
+}
+
+// printConfirmations prints a per-fixture report of submissions read back from a target account
+func printConfirmations(name string, confirmations []target.Confirmation) {
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(w, "Target: %s\n", name)
+	fmt.Fprintln(w, "FIXTURE\tSUBMITTED\tSAVED\tSTATUS\tATTEMPTS")
+	for _, c := range confirmations {
+		saved := "-"
+		if c.Saved != nil {
+			saved = tip(*c.Saved)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\n", c.Prediction.Fixture, tip(c.Prediction), saved, c.Status, c.Attempts)
+	}
+	fmt.Fprintln(w)
+
+	w.Flush()
+
+}
+
+// tip formats a prediction as its winner and margin
+func tip(p domain.Prediction) string {
+
+	if p.Winner == domain.Draw {
+		return domain.Draw
+	}
+
+	return fmt.Sprintf("%s by %d", p.Winner, p.Margin)
+
+}
@@ -2,32 +2,91 @@ package main
 
 import (
 	"brubot/config"
+	"brubot/internal/archive"
+	"brubot/internal/domain"
 	"brubot/internal/helpers"
 	"brubot/internal/sources"
 	"brubot/internal/target"
+	"brubot/internal/teams"
 	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
 )
 
 func main() {
 
+	// Initialise brubot
+	helpers.LoggerInit()
+
+	// Commands other than a regular run are selected by the first argument
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "weights":
+			weights(os.Args[2:])
+			return
+		case "probe":
+			probe(os.Args[2:])
+			return
+		}
+	}
+
+	// Fatal exits skip deferred calls, so run returns its failure rather than exiting
+	if err := run(os.Args[1:]); err != nil {
+		helpers.Logger.Fatal(err)
+	}
+
+}
+
+// run retrieves results, fixtures and source predictions for the current round
+// and submits aggregated predictions to every configured target:
+//
+//	brubot (-submit | -dry-run) [-replay run-id|dir]
+//
+// Every planned submission is shown against the targets current tip, predictions are only
+// sent with -submit. Runs given neither -submit nor -dry-run are dry runs which fail, so
+// scheduled runs predating -submit do not silently stop submitting.
+//
+// When replaying, responses are served from an archived run (see internal/archive) in place
+// of sources and the target, the round is taken from the archived run and nothing is
+// recorded to backend or submitted.
+//
+// Failures after the response archive is created are returned, allowing the archive
+// to be pruned on every run.
+func run(args []string) error {
+
 	var err error
+	var replay *archive.Replay
+
+	flags := flag.NewFlagSet("brubot", flag.ExitOnError)
+	replayRun := flags.String("replay", "", "re-execute an archived run (run ID or run directory) without network access")
+	submit := flags.Bool("submit", false, "submit predictions to targets")
+	dryRun := flags.Bool("dry-run", false, "only show planned submissions, nothing is submitted")
+	flags.Parse(args)
+
+	if *submit && *dryRun {
+		return errors.New("-submit and -dry-run cannot both be given")
+	}
+	if !*submit && !*dryRun {
+		helpers.Logger.Warn("Neither -submit nor -dry-run given, nothing will be submitted and the run will fail")
+	}
 
 	var globalConfig config.GlobalConfig
-	var targetConfig config.TargetConfig
+	var targetsConfig []config.TargetConfig
 	var sourcesConfig config.SourcesConfig
 
 	var db *sql.DB
 	var roundID int
 	var previousRoundID int
-	var margins map[string]int
+	var margins []sources.Margin
+	var accounts []target.Account
+	var fixtures []domain.Fixture
+	var accountFixtures = make(map[string][]domain.Fixture)
 
-	target := new(target.Target)
-	sources := new(sources.Sources)
-	margins = make(map[string]int)
+	brubotSources := new(sources.Sources)
 
-	// Initialise brubot
-	helpers.LoggerInit()
-	globalConfig, targetConfig, sourcesConfig, err = helpers.ConfigInit()
+	globalConfig, targetsConfig, sourcesConfig, err = helpers.ConfigInit()
 	if err != nil {
 		helpers.Logger.Panic("A failure occurred initialising config: ", err)
 	}
@@ -39,50 +98,198 @@ func main() {
 
 	defer db.Close()
 
-	roundID, err = helpers.GetCurrentRound(db)
-	if err != nil {
-		helpers.Logger.Panic("A failure occurred determining roundID: ", err)
+	if *replayRun != "" {
+		// A replayed run uses the round it was archived for
+		if replay, err = archive.OpenReplay(globalConfig, *replayRun); err != nil {
+			helpers.Logger.Panic("A failure occurred opening archived run: ", err)
+		}
+		roundID = replay.Manifest.RoundID
+		helpers.Logger.Infof("Replaying archived run: %s round: %d", replay.Manifest.RunID, roundID)
+	} else {
+		roundID, err = helpers.GetCurrentRound(db)
+		if err != nil {
+			helpers.Logger.Panic("A failure occurred determining roundID: ", err)
+		}
 	}
 	previousRoundID = roundID - 1
 
-	// Initialize target and get fixutres
-	target.Init(globalConfig, targetConfig)
-
-	if err = target.Authenticate(); err != nil {
-		helpers.Logger.Fatal("A failure occurred authenticating to target: ", err)
+	// Raw source and target responses are archived per run when enabled, replayed runs
+	// are not archived again
+	var responses *archive.Archive
+	if replay == nil {
+		responses, err = archive.New(globalConfig, roundID)
+		if err != nil {
+			helpers.Logger.Panic("A failure occurred initialising response archive: ", err)
+		}
+	}
+	if responses != nil {
+		helpers.Logger.Infof("Archiving responses for run: %s", responses.RunID())
+		defer func() {
+			if pruneErr := responses.Prune(); pruneErr != nil {
+				helpers.Logger.Warn("A failure occurred pruning response archive: ", pruneErr)
+			}
+		}()
 	}
+	brubotSources.Archive = responses
 
-	// Gets results from previous rounds fixtures and update db
-	err = target.Results(previousRoundID, db)
+	// Canonical teams resolve every scraped team name to a stable team ID
+	registry, err := teams.New(globalConfig)
 	if err != nil {
-		helpers.Logger.Fatal("Failure extracting results from target: ", err)
+		helpers.Logger.Panic("A failure occurred initialising teams: ", err)
+	}
+	if globalConfig.TeamsFromDB {
+		if err = registry.LoadDB(db); err != nil {
+			helpers.Logger.Panic("A failure occurred loading teams from database: ", err)
+		}
 	}
 
-	// Gets current fixtures for this round
-	err = target.Fixtures(roundID)
-	if err != nil {
-		helpers.Logger.Fatal("Failure extracting fixtures from target: ", err)
+	// Initialize target accounts and get fixutres
+	for _, targetConfig := range targetsConfig {
+		competition, err := target.Accounts(targetConfig, target.Env{Global: globalConfig, Teams: registry, Archive: responses})
+		if err != nil {
+			return fmt.Errorf("A failure occurred initialising target: %w", err)
+		}
+		accounts = append(accounts, competition...)
+	}
+
+	// Account failures are isolated, failed accounts are skipped for the remainder of the run
+	failed := make(map[string]error)
+	// Results are retrieved once per competition, by its first authenticated account
+	resulted := make(map[string]bool)
+
+	for _, account := range accounts {
+
+		if replay != nil {
+			replayer, ok := account.Target.(target.Replayer)
+			if !ok {
+				failed[account.Name()] = errors.New("target does not support replay")
+				continue
+			}
+			err = replayer.Replay(replay)
+		} else {
+			err = account.Authenticate()
+		}
+		if err != nil {
+			helpers.Logger.Errorf("A failure occurred authenticating to target: %s: %v", account.Name(), err)
+			failed[account.Name()] = err
+			continue
+		}
+
+		// Gets results from previous rounds fixtures and update db
+		if !resulted[account.Competition] {
+			results, err := account.Results(previousRoundID)
+			if err != nil {
+				helpers.Logger.Errorf("Failure extracting results from target: %s: %v", account.Name(), err)
+				failed[account.Name()] = err
+				continue
+			}
+			resulted[account.Competition] = true
+			if replay != nil {
+				helpers.Logger.Info("Replaying archived run, results update skipped")
+			} else if err = target.UpdateResults(db, account.Competition, previousRoundID, results); err != nil {
+				helpers.Logger.Errorf("Failure updating results from target: %s: %v", account.Name(), err)
+			}
+		}
+
+		// Gets current fixtures for this round
+		roundFixtures, err := account.Fixtures(roundID)
+		if err != nil {
+			helpers.Logger.Errorf("Failure extracting fixtures from target: %s: %v", account.Name(), err)
+			failed[account.Name()] = err
+			continue
+		}
+		fixtures = append(fixtures, roundFixtures...)
+		accountFixtures[account.Name()] = roundFixtures
+
+	}
+
+	if len(failed) == len(accounts) {
+		return fmt.Errorf("A failure occurred on every target account: %v", failed)
 	}
 
 	// Initialize sources and retrieve predictions
-	sources.Init(globalConfig, sourcesConfig)
+	if err = brubotSources.Init(globalConfig, sourcesConfig, registry); err != nil {
+		return fmt.Errorf("A failure occurred initialising source(s): %w", err)
+	}
+	if replay != nil {
+		brubotSources.Replay(replay)
+	}
+	// Sources are health checked against the targets fixtures
+	brubotSources.Expect(fixtures)
 
-	// Retrieve predicted margins for all fixtures in a round, per source
-	err = sources.Predictions(roundID, db)
+	// Retrieve predicted margins for all fixtures in a round, per source (concurrently).
+	// Failed sources are dropped, only failing the run when the sources policy is not met.
+	err = brubotSources.Predictions(roundID, db)
 	if err != nil {
-		helpers.Logger.Fatal("A failure occurred retrieving predictions from source(s): ", err)
+		return fmt.Errorf("A failure occurred retrieving predictions from source(s): %w", err)
 	}
 
-	// Generate weighted margin predictions for all sources
-	margins, err = sources.Margins(roundID)
+	// Generate aggregated margin predictions for all sources, recorded with the strategy used
+	margins, err = brubotSources.Margins(roundID, db)
 	if err != nil {
-		helpers.Logger.Error("A failure occurred generating predictions: ", err)
+		return fmt.Errorf("A failure occurred generating predictions: %w", err)
 	}
 
-	// Submit generated margins to target
-	err = target.Predictions(margins)
-	if err != nil {
-		helpers.Logger.Fatal("A failure occurred submitting predictions: ", err)
+	// Submit generated margins to each target account, accounts with their own
+	// aggregation strategy are submitted margins aggregated using it
+	for _, account := range accounts {
+
+		if _, ok := failed[account.Name()]; ok {
+			continue
+		}
+
+		accountMargins := margins
+		if account.Strategy != "" {
+			aggregator, err := sources.NewAggregator(account.Strategy, account.Trim)
+			if err != nil {
+				helpers.Logger.Errorf("A failure occurred generating predictions for target: %s: %v", account.Name(), err)
+				failed[account.Name()] = err
+				continue
+			}
+			if accountMargins, err = brubotSources.MarginsWith(roundID, db, aggregator); err != nil {
+				helpers.Logger.Errorf("A failure occurred generating predictions for target: %s: %v", account.Name(), err)
+				failed[account.Name()] = err
+				continue
+			}
+		}
+
+		predictions := sources.ToPredictions(accountMargins)
+
+		// Show planned submissions against current tips ahead of submitting
+		submissions, err := account.Plan(predictions)
+		if err != nil {
+			helpers.Logger.Warnf("Planned submission to target: %s is incomplete: %v", account.Name(), err)
+		}
+		current, err := account.CurrentTips()
+		if err != nil && err != target.ErrCurrentTipsUnsupported {
+			helpers.Logger.Warnf("A failure occurred retrieving current tips from target: %s: %v", account.Name(), err)
+		}
+		printChanges(account.Name(), target.Diff(accountFixtures[account.Name()], submissions, current))
+
+		if replay != nil {
+			helpers.Logger.Infof("Replaying archived run, prediction submission to target: %s skipped", account.Name())
+			continue
+		}
+		if !*submit {
+			helpers.Logger.Infof("Dry run, prediction submission to target: %s skipped", account.Name())
+			continue
+		}
+		confirmations, err := account.Submit(predictions)
+		printConfirmations(account.Name(), confirmations)
+		if err != nil {
+			helpers.Logger.Errorf("A failure occurred submitting predictions to target: %s: %v", account.Name(), err)
+			failed[account.Name()] = err
+		}
+
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("A failure occurred on %d of %d target accounts: %v", len(failed), len(accounts), failed)
 	}
+	if replay == nil && !*submit && !*dryRun {
+		return errors.New("Nothing was submitted, predictions are only submitted with -submit (or pass -dry-run to review them)")
+	}
+
+	return nil
 
 }
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"brubot/internal/archive"
+	"brubot/internal/helpers"
+	"brubot/internal/sources"
+	"brubot/internal/target"
+	"brubot/internal/teams"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// probe applies a source or target parser block from config to a saved page or archived
+// response, printing parsed fixtures alongside match counts per parser key (selector or
+// path) so parsers can be authored offline:
+//
+//	brubot probe (-source name | -target fixtures|results [-name target]) (-file path | -archive run [-entry seq]) [-round roundID]
+//
+// Without -entry the responses within an archived run are listed. Teams are resolved using
+// global.teams only, the backend is not used.
+func probe(args []string) {
+
+	globalConfig, targetsConfig, sourcesConfig, err := helpers.ConfigInit()
+	if err != nil {
+		helpers.Logger.Panic("A failure occurred initialising config: ", err)
+	}
+
+	flags := flag.NewFlagSet("probe", flag.ExitOnError)
+	sourceName := flags.String("source", "", "source to apply the predictions parser of")
+	targetPage := flags.String("target", "", "target page to apply the parser of: fixtures or results")
+	targetName := flags.String("name", "", "target to apply the parser of, defaults to the first target")
+	file := flags.String("file", "", "saved page to parse")
+	run := flags.String("archive", "", "archived run (run ID or run directory) holding the response to parse")
+	entry := flags.Int("entry", 0, "sequence of the archived response to parse")
+	round := flags.Int("round", 0, "round ID of the page, defaults to the archived runs round")
+	flags.Parse(args)
+
+	if (*sourceName == "") == (*targetPage == "") || (*file == "") == (*run == "") {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	var page archive.Page
+	roundID := *round
+
+	if *file != "" {
+		if page, err = archive.ReadPage(*file); err != nil {
+			helpers.Logger.Fatal("A failure occurred reading saved page: ", err)
+		}
+	} else {
+		replay, err := archive.OpenReplay(globalConfig, *run)
+		if err != nil {
+			helpers.Logger.Fatal("A failure occurred opening archived run: ", err)
+		}
+		if *entry == 0 {
+			listEntries(replay.Entries())
+			return
+		}
+		if page, err = replay.Page(*entry); err != nil {
+			helpers.Logger.Fatal("A failure occurred reading archived response: ", err)
+		}
+		if roundID == 0 {
+			roundID = replay.Manifest.RoundID
+		}
+	}
+
+	registry, err := teams.New(globalConfig)
+	if err != nil {
+		helpers.Logger.Fatal("A failure occurred initialising teams: ", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	var matches map[string]int
+	var probeErr error
+
+	if *sourceName != "" {
+
+		brubotSources := new(sources.Sources)
+		if err = brubotSources.Init(globalConfig, sourcesConfig, registry); err != nil {
+			helpers.Logger.Fatal("A failure occurred initialising source(s): ", err)
+		}
+
+		var src *sources.Source
+		if src, probeErr = brubotSources.Probe(*sourceName, roundID, page); src != nil {
+			fmt.Fprintln(w, "LEFT\tRIGHT\tWINNER\tMARGIN")
+			for _, p := range src.Round.Predictions {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", p.LeftTeam, p.RightTeam, p.Winner, p.Margin)
+			}
+			matches = src.Matches()
+		}
+
+	} else {
+
+		if len(targetsConfig) == 0 {
+			helpers.Logger.Fatal("No targets are configured")
+		}
+		targetConfig := targetsConfig[0]
+		if *targetName != "" {
+			names := make([]string, 0, len(targetsConfig))
+			found := false
+			for idx := range targetsConfig {
+				names = append(names, targetsConfig[idx].Name)
+				if targetsConfig[idx].Name == *targetName {
+					targetConfig = targetsConfig[idx]
+					found = true
+				}
+			}
+			if !found {
+				helpers.Logger.Fatalf("Unknown target: %q, configured targets: %s", *targetName, strings.Join(names, ", "))
+			}
+		}
+
+		t, err := target.New(targetConfig, target.Env{Global: globalConfig, Teams: registry})
+		if err != nil {
+			helpers.Logger.Fatal("A failure occurred initialising target: ", err)
+		}
+		prober, ok := t.(target.Prober)
+		if !ok {
+			helpers.Logger.Fatalf("Target: %s does not support probing", t.Name())
+		}
+
+		var result target.ProbeResult
+		result, probeErr = prober.Probe(*targetPage, roundID, page)
+		if *targetPage == target.ProbeResults {
+			fmt.Fprintln(w, "TOURNAMENT\tLEFT\tRIGHT\tWINNER\tMARGIN")
+			for _, r := range result.Results {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\n", r.Tournament, r.LeftTeam, r.RightTeam, r.Winner, r.Margin)
+			}
+		} else {
+			fmt.Fprintln(w, "TOURNAMENT\tLEFT\tRIGHT")
+			for _, f := range result.Fixtures {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", f.Tournament, f.LeftTeam, f.RightTeam)
+			}
+		}
+		matches = result.Matches
+
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "KEY\tMATCHES")
+	keys := make([]string, 0, len(matches))
+	for key := range matches {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s\t%d\n", key, matches[key])
+	}
+	w.Flush()
+
+	if probeErr != nil {
+		fmt.Fprintln(os.Stderr, probeErr)
+		os.Exit(1)
+	}
+
+}
+
+// listEntries prints the responses archived within a run
+func listEntries(entries []archive.Entry) {
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ENTRY\tNAME\tROUND\tSTATUS\tURL")
+	for _, e := range entries {
+		fmt.Fprintf(w, "%d\t%s\t%d\t%d\t%s\n", e.Seq, e.Name, e.RoundID, e.Status, e.URL)
+	}
+	w.Flush()
+
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"brubot/internal/helpers"
+	"brubot/internal/sources"
+	"flag"
+	"fmt"
+)
+
+// weights fits source weights from historical prediction accuracy, printing suggested
+// config and optionally writing weights to the backend for use at runtime:
+//
+//	brubot weights [-window rounds] [-round roundID] [-target name] [-write]
+func weights(args []string) {
+
+	globalConfig, targetsConfig, sourcesConfig, err := helpers.ConfigInit()
+	if err != nil {
+		helpers.Logger.Panic("A failure occurred initialising config: ", err)
+	}
+
+	defaultWindow := sourcesConfig.Weights.Window
+	if defaultWindow == 0 {
+		defaultWindow = 10
+	}
+
+	flags := flag.NewFlagSet("weights", flag.ExitOnError)
+	window := flags.Int("window", defaultWindow, "number of past rounds to measure source accuracy over")
+	round := flags.Int("round", 0, "round to fit weights for, defaults to the current round")
+	competition := flags.String("target", "", "target (competition) whose results sources are measured against, defaults to the first target")
+	write := flags.Bool("write", false, "write fitted weights to the weights table")
+	flags.Parse(args)
+
+	if *competition == "" && len(targetsConfig) > 0 {
+		*competition = targetsConfig[0].Name
+	}
+
+	db, err := helpers.DBInit(globalConfig)
+	if err != nil {
+		helpers.Logger.Panic("A failure occurred initialising database connection: ", err)
+	}
+
+	defer db.Close()
+
+	roundID := *round
+	if roundID == 0 {
+		if roundID, err = helpers.GetCurrentRound(db); err != nil {
+			helpers.Logger.Panic("A failure occurred determining roundID: ", err)
+		}
+	}
+
+	fitted, err := sources.FitWeights(db, *competition, roundID, *window)
+	if err != nil {
+		helpers.Logger.Fatal("A failure occurred fitting source weights: ", err)
+	}
+
+	fmt.Print(sources.SuggestedYAML(fitted))
+
+	if *write {
+		if err = sources.UpdateWeights(db, roundID, fitted); err != nil {
+			helpers.Logger.Fatal("A failure occurred writing source weights: ", err)
+		}
+		helpers.Logger.Infof("Fitted weights written for round: %d", roundID)
+	}
+
+}
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/spf13/viper"
@@ -25,20 +27,66 @@ type GlobalConfig struct {
 		SSLMode  string `mapstructure:"sslmode"`
 	} `mapstructure:"db"`
 	UserAgent string `mapstructure:"userAgent"`
+	// Canonical teams, with aliases applying globally, per source (by source name) or to the target
+	Teams []struct {
+		ID         string              `mapstructure:"id"`
+		Name       string              `mapstructure:"name"`
+		Tournament string              `mapstructure:"tournament"`
+		Aliases    []string            `mapstructure:"aliases"`
+		Sources    map[string][]string `mapstructure:"sources"`
+		Target     []string            `mapstructure:"target"`
+	} `mapstructure:"teams"`
+	TeamsFromDB bool `mapstructure:"teamsFromDB"`
+	// Raw response archiving for source and target clients
+	Archive struct {
+		Enabled       bool     `mapstructure:"enabled"`
+		Dir           string   `mapstructure:"dir"`
+		RetentionDays int      `mapstructure:"retentionDays"`
+		Redact        []string `mapstructure:"redact"`
+	} `mapstructure:"archive"`
 }
 
-// TargetConfig maps to target config stanza
+// TargetConfig maps to a target config stanza, either the single target
+// stanza or each element of the targets list
 type TargetConfig struct {
-	UseGlobals bool `mapstructure:"useGlobals"`
-	Auth       struct {
+	Name       string `mapstructure:"name"`
+	Kind       string `mapstructure:"kind"`
+	UseGlobals bool   `mapstructure:"useGlobals"`
+	Drift      string `mapstructure:"drift"`
+	Verify     struct {
+		Retries int `mapstructure:"retries"`
+	} `mapstructure:"verify"`
+	Auth struct {
 		URL            string            `mapstructure:"url"`
 		Parameters     map[string]string `mapstructure:"parameters"`
-		PasswordEncode bool              `mapstructure:"passwordEncode"`
+		PasswordEncode bool              `mapstructure:"passwordEncode"` // Deprecated, every field is form encoded
 		Method         string            `mapstructure:"method"`
 		UserAgent      string            `mapstructure:"userAgent"`
 		ErrorMsg       string            `mapstructure:"errorMsg"`
 		Timeout        time.Duration     `mapstructure:"timeout"`
 		Headers        map[string]string `mapstructure:"headers"`
+		Mode           string            `mapstructure:"mode"` // form (default), json or apikey
+		// Token sent as a header with every request for json and apikey auth modes
+		Token struct {
+			Path       string `mapstructure:"path"`
+			ExpiryPath string `mapstructure:"expiryPath"`
+			Header     string `mapstructure:"header"`
+			Prefix     string `mapstructure:"prefix"`
+		} `mapstructure:"token"`
+		// Login page fetched ahead of authenticating, its hidden inputs (i.e. CSRF tokens) are
+		// submitted along with parameters
+		PreLogin struct {
+			URL      string `mapstructure:"url"`
+			Selector string `mapstructure:"selector"`
+		} `mapstructure:"preLogin"`
+		// Sessions persisted between runs when dir is set, encrypted with the base64 key held by
+		// the keyEnv environment variable (BRUBOT_SESSION_KEY by default). Sessions are reused
+		// for maxAgeHours (24 by default), negative for no limit.
+		Session struct {
+			Dir         string `mapstructure:"dir"`
+			KeyEnv      string `mapstructure:"keyEnv"`
+			MaxAgeHours int    `mapstructure:"maxAgeHours"`
+		} `mapstructure:"session"`
 	} `mapstructure:"auth"`
 	Client struct {
 		UserAgent           string            `mapstructure:"userAgent"`
@@ -55,16 +103,45 @@ type TargetConfig struct {
 			Predictions map[string]string `mapstructure:"predictions"`
 		} `mapstructure:"parser"`
 	} `mapstructure:"client"`
+	Accounts []AccountConfig `mapstructure:"accounts"`
+}
+
+// AccountConfig is a single entrant within a target (competition), parameters (credentials)
+// are merged over auth.parameters and aggregation overrides sources.aggregation
+type AccountConfig struct {
+	Name        string            `mapstructure:"name"`
+	Parameters  map[string]string `mapstructure:"parameters"`
+	Aggregation struct {
+		Strategy string  `mapstructure:"strategy"`
+		Trim     float64 `mapstructure:"trim"`
+	} `mapstructure:"aggregation"`
 }
 
 // SourcesConfig holds settings for every defined source (s1..sX)
 // Sources slice unmarshals from sources.endpoints list
 type SourcesConfig struct {
+	Timeout time.Duration `mapstructure:"timeout"`
+	Policy  struct {
+		MinSources int      `mapstructure:"minSources"`
+		Required   []string `mapstructure:"required"`
+		Drift      string   `mapstructure:"drift"`
+	} `mapstructure:"policy"`
+	Aggregation struct {
+		Strategy string  `mapstructure:"strategy"`
+		Trim     float64 `mapstructure:"trim"`
+	} `mapstructure:"aggregation"`
+	Weights struct {
+		Source string `mapstructure:"source"`
+		Window int    `mapstructure:"window"`
+	} `mapstructure:"weights"`
 	Sources []struct {
-		Name       string  `mapstructure:"name"`
-		Tournament string  `mapstructure:"tournament"`
-		Weight     float64 `mapstructure:"weight"`
-		UseGlobals bool    `mapstructure:"useGlobals"`
+		Name       string        `mapstructure:"name"`
+		Kind       string        `mapstructure:"kind"`
+		Tournament string        `mapstructure:"tournament"`
+		Weight     float64       `mapstructure:"weight"`
+		UseGlobals bool          `mapstructure:"useGlobals"`
+		Timeout    time.Duration `mapstructure:"timeout"`
+		Rounds     RoundsConfig  `mapstructure:"rounds"`
 		Client     struct {
 			UserAgent           string            `mapstructure:"userAgent"`
 			IgnoreRobots        bool              `mapstructure:"ignoreRobots"`
@@ -80,6 +157,18 @@ type SourcesConfig struct {
 	} `mapstructure:"endpoints"`
 }
 
+// RoundsConfig maps our round numbering onto a sources own round numbering,
+// table keys are our round IDs and dates are formatted as 2006-01-02
+type RoundsConfig struct {
+	Offset *int           `mapstructure:"offset"` // Unset falls back to a legacy round_offset parser option
+	Table  map[string]int `mapstructure:"table"`
+	Dates  []struct {
+		From  string `mapstructure:"from"`
+		To    string `mapstructure:"to"`
+		Round int    `mapstructure:"round"`
+	} `mapstructure:"dates"`
+}
+
 // Init parses config file
 func (p *Parameters) Init() error {
 
@@ -97,8 +186,10 @@ func (p *Parameters) Init() error {
 
 // ParseConfig parses and populates all config parameters
 // unmarshalling config parameters for globals, targets and sources
-// into their respective Config structs
-func (p *Parameters) ParseConfig(g *GlobalConfig, t *TargetConfig, s *SourcesConfig) error {
+// into their respective Config structs.
+//
+// Targets are read from the targets list, falling back to the single target stanza.
+func (p *Parameters) ParseConfig(g *GlobalConfig, t *[]TargetConfig, s *SourcesConfig) error {
 
 	var err error
 
@@ -109,11 +200,31 @@ func (p *Parameters) ParseConfig(g *GlobalConfig, t *TargetConfig, s *SourcesCon
 		return err
 	}
 
-	p.Target = p.Config.Sub("target")
+	if p.Config.IsSet("targets") {
+		if err = p.Config.UnmarshalKey("targets", t); err != nil {
+			return err
+		}
+	} else {
+		var target TargetConfig
+		p.Target = p.Config.Sub("target")
+		if p.Target == nil {
+			return errors.New("no target or targets configured")
+		}
+		if err = p.Target.Unmarshal(&target); err != nil {
+			return err
+		}
+		*t = []TargetConfig{target}
+	}
 
-	err = p.Target.Unmarshal(&t)
-	if err != nil {
-		return err
+	// Unnamed targets are named by position, a single target is simply "target"
+	for idx := range *t {
+		if (*t)[idx].Name != "" {
+			continue
+		}
+		(*t)[idx].Name = "target"
+		if len(*t) > 1 {
+			(*t)[idx].Name = fmt.Sprintf("target%d", idx+1)
+		}
 	}
 
 	p.Sources = p.Config.Sub("sources")
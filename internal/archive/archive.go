@@ -0,0 +1,272 @@
+/*
+   Raw response archiving, every body fetched by the source and target colly clients
+   is stored so we can see exactly what a page looked like at scrape time.
+
+   Layout within the archive directory:
+
+     objects/<sha256[:2]>/<sha256>   response bodies, content-addressed (deduplicated)
+     runs/<runID>/manifest.json      run metadata (started, round ID)
+     runs/<runID>/entries.jsonl      one Entry per fetched response, in fetch order
+*/
+
+package archive
+
+import (
+	"brubot/config"
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// runIDLayout formats run IDs from the time a run started, runs started within the same
+// second are suffixed with a sequence (i.e. 20200718T090000Z-2)
+const runIDLayout = "20060102T150405Z"
+
+// redacted replaces the value of any header considered secret
+const redacted = "REDACTED"
+
+// defaultRedact lists headers (case insensitive substrings) always redacted
+var defaultRedact = []string{"cookie", "authorization", "token", "secret", "api-key", "apikey", "session", "csrf"}
+
+// Archive stores fetched responses for a single run
+type Archive struct {
+	dir       string        // Archive root directory
+	runID     string        // Current run, entries are recorded within runs/<runID>
+	retention time.Duration // Runs older than retention are removed by Prune, 0 keeps everything
+	redact    []string      // Header name substrings to redact
+	mu        sync.Mutex    // Sources are fetched concurrently
+	seq       int           // Sequence of the last recorded entry
+}
+
+// Manifest describes an archived run
+type Manifest struct {
+	RunID   string    `json:"runId"`
+	Started time.Time `json:"started"`
+	RoundID int       `json:"roundId"`
+}
+
+// Entry describes a single archived response
+type Entry struct {
+	Seq            int         `json:"seq"`
+	Time           time.Time   `json:"time"`
+	Name           string      `json:"name"`    // Source name, or target
+	RoundID        int         `json:"roundId"` // Round ID the request was made for
+	Method         string      `json:"method"`
+	URL            string      `json:"url"`
+	Status         int         `json:"status"`
+	RequestHeaders http.Header `json:"requestHeaders"`
+	Headers        http.Header `json:"headers"`
+	Body           string      `json:"body"` // sha256 of the body, see objects/
+}
+
+// New creates an archive for a new run within the global.archive directory,
+// returning nil when archiving is disabled
+func New(globalConfig config.GlobalConfig, roundID int) (*Archive, error) {
+
+	if !globalConfig.Archive.Enabled {
+		return nil, nil
+	}
+
+	a := &Archive{
+		dir:       globalConfig.Archive.Dir,
+		retention: time.Hour * 24 * time.Duration(globalConfig.Archive.RetentionDays),
+		redact:    append(defaultRedact, globalConfig.Archive.Redact...),
+	}
+	if a.dir == "" {
+		a.dir = "archive"
+	}
+
+	if err := os.MkdirAll(filepath.Join(a.dir, "runs"), 0750); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Join(a.dir, "objects"), 0750); err != nil {
+		return nil, err
+	}
+
+	// Run directories are created exclusively, so concurrent runs never share a run
+	started := time.Now().UTC()
+	a.runID = started.Format(runIDLayout)
+	for seq := 2; ; seq++ {
+		err := os.Mkdir(filepath.Join(a.dir, "runs", a.runID), 0750)
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		a.runID = fmt.Sprintf("%s-%d", started.Format(runIDLayout), seq)
+	}
+
+	manifest, err := json.MarshalIndent(Manifest{RunID: a.runID, Started: started, RoundID: roundID}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err = ioutil.WriteFile(filepath.Join(a.dir, "runs", a.runID, "manifest.json"), manifest, 0640); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+
+}
+
+// RunID returns the ID of the run being archived
+func (a *Archive) RunID() string {
+	if a == nil {
+		return ""
+	}
+	return a.runID
+}
+
+// Record stores a fetched response body and its metadata against the current run,
+// secret request and response headers are redacted
+func (a *Archive) Record(entry Entry, body []byte) error {
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	sum := sha256.Sum256(body)
+	entry.Body = hex.EncodeToString(sum[:])
+
+	objectDir := filepath.Join(a.dir, "objects", entry.Body[:2])
+	if err := os.MkdirAll(objectDir, 0750); err != nil {
+		return err
+	}
+	objectPath := filepath.Join(objectDir, entry.Body)
+	// Content-addressed, an existing object already holds this body
+	if _, err := os.Stat(objectPath); os.IsNotExist(err) {
+		if err = ioutil.WriteFile(objectPath, body, 0640); err != nil {
+			return err
+		}
+	}
+
+	a.seq++
+	entry.Seq = a.seq
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+	entry.RequestHeaders = a.redactHeaders(entry.RequestHeaders)
+	entry.Headers = a.redactHeaders(entry.Headers)
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filepath.Join(a.dir, "runs", a.runID, "entries.jsonl"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+
+	return err
+
+}
+
+// redactHeaders returns a copy of headers with secret values replaced
+func (a *Archive) redactHeaders(headers http.Header) http.Header {
+
+	if headers == nil {
+		return nil
+	}
+
+	clean := make(http.Header, len(headers))
+	for name, values := range headers {
+		clean[name] = values
+		for _, secret := range a.redact {
+			if strings.Contains(strings.ToLower(name), strings.ToLower(secret)) {
+				clean[name] = []string{redacted}
+				break
+			}
+		}
+	}
+
+	return clean
+
+}
+
+// Prune removes runs started before the retention period, along with any
+// objects no longer referenced by a remaining run
+func (a *Archive) Prune() error {
+
+	if a == nil || a.retention == 0 {
+		return nil
+	}
+
+	runs, err := ioutil.ReadDir(filepath.Join(a.dir, "runs"))
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().UTC().Add(-a.retention)
+	referenced := make(map[string]bool)
+
+	for _, run := range runs {
+
+		started, parseErr := time.Parse(runIDLayout, strings.SplitN(run.Name(), "-", 2)[0])
+		if parseErr == nil && started.Before(cutoff) {
+			if err = os.RemoveAll(filepath.Join(a.dir, "runs", run.Name())); err != nil {
+				return err
+			}
+			continue
+		}
+
+		entries, err := ReadEntries(filepath.Join(a.dir, "runs", run.Name()))
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			referenced[entry.Body] = true
+		}
+
+	}
+
+	return filepath.Walk(filepath.Join(a.dir, "objects"), func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		if !referenced[info.Name()] {
+			return os.Remove(path)
+		}
+		return nil
+	})
+
+}
+
+// ReadEntries reads every entry recorded within a run directory
+func ReadEntries(runDir string) ([]Entry, error) {
+
+	var entries []Entry
+
+	f, err := os.Open(filepath.Join(runDir, "entries.jsonl"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if err = json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed reading archive entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+
+}
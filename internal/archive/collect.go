@@ -0,0 +1,52 @@
+package archive
+
+import (
+	"brubot/internal/helpers"
+	"net/http"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// Collect records every response received by collector against the current run,
+// name identifies the client (source name or target) and roundID returns the
+// round ID requests are currently being made for.
+//
+// Responses are archived on a best effort basis, failures are logged rather than
+// failing the request. Collect is a no-op on a nil (disabled) archive.
+func (a *Archive) Collect(collector *colly.Collector, name string, roundID func() int) {
+
+	if a == nil {
+		return
+	}
+
+	record := func(r *colly.Response) {
+
+		entry := Entry{
+			Name:    name,
+			RoundID: roundID(),
+			Method:  r.Request.Method,
+			URL:     r.Request.URL.String(),
+			Status:  r.StatusCode,
+		}
+		if r.Request.Headers != nil {
+			entry.RequestHeaders = *r.Request.Headers
+		}
+		if r.Headers != nil {
+			entry.Headers = http.Header(*r.Headers)
+		}
+
+		if err := a.Record(entry, r.Body); err != nil {
+			helpers.Logger.Warnf("Failed archiving response from %s: %v", entry.URL, err)
+		}
+
+	}
+
+	collector.OnResponse(record)
+	// Error responses (i.e. 4xx/5xx) never reach OnResponse
+	collector.OnError(func(r *colly.Response, _ error) {
+		if r != nil && r.Request != nil && r.StatusCode != 0 {
+			record(r)
+		}
+	})
+
+}
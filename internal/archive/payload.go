@@ -0,0 +1,29 @@
+package archive
+
+import (
+	"brubot/internal/helpers"
+)
+
+// Methods recorded against payloads read locally rather than fetched, see RecordPayload
+const (
+	MethodFile = "FILE" // uri is the path of the file read
+	MethodExec = "EXEC" // uri is the command and its arguments
+)
+
+// RecordPayload records a payload read from a local file or command against the current run,
+// so runs using file and exec sources can be replayed without reading files or running
+// commands. name identifies the source and method is MethodFile or MethodExec.
+//
+// As with Collect, payloads are archived on a best effort basis and RecordPayload is a
+// no-op on a nil (disabled) archive.
+func (a *Archive) RecordPayload(name string, roundID int, method string, uri string, body []byte) {
+
+	if a == nil {
+		return
+	}
+
+	if err := a.Record(Entry{Name: name, RoundID: roundID, Method: method, URL: uri}, body); err != nil {
+		helpers.Logger.Warnf("Failed archiving payload from %s: %v", uri, err)
+	}
+
+}
@@ -0,0 +1,227 @@
+package archive
+
+import (
+	"brubot/config"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Replay serves responses from an archived run in place of the network,
+// allowing a past run to be re-executed deterministically
+type Replay struct {
+	Manifest Manifest           // Archived run metadata, including the round ID
+	dir      string             // Archive root directory, holding objects/
+	entries  map[string][]Entry // Archived entries by request (method and URL), in fetch order
+	served   map[string]int     // Entries served so far by request
+	mu       sync.Mutex         // Sources are replayed concurrently
+}
+
+// OpenReplay opens an archived run for replay, run is either a run ID within the
+// global.archive directory or the path to a run directory
+func OpenReplay(globalConfig config.GlobalConfig, run string) (*Replay, error) {
+
+	runDir := run
+	if info, err := os.Stat(runDir); err != nil || !info.IsDir() {
+		root := globalConfig.Archive.Dir
+		if root == "" {
+			root = "archive"
+		}
+		runDir = filepath.Join(root, "runs", run)
+	}
+
+	manifest, err := ioutil.ReadFile(filepath.Join(runDir, "manifest.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed reading archived run %s: %w", run, err)
+	}
+
+	r := &Replay{
+		// Run directories live within <archive>/runs/
+		dir:     filepath.Dir(filepath.Dir(filepath.Clean(runDir))),
+		entries: make(map[string][]Entry),
+		served:  make(map[string]int),
+	}
+	if err = json.Unmarshal(manifest, &r.Manifest); err != nil {
+		return nil, fmt.Errorf("failed decoding archived run manifest: %w", err)
+	}
+
+	entries, err := ReadEntries(runDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		key := entry.Method + " " + entry.URL
+		r.entries[key] = append(r.entries[key], entry)
+	}
+
+	return r, nil
+
+}
+
+// RoundTrip serves the archived response for a request, requests made more than once
+// within the archived run are served in the order they were archived, repeating the last
+func (r *Replay) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	entry, err := r.next(req.Method, req.URL.String())
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := r.page(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return page.RoundTrip(req)
+
+}
+
+// Payload returns the archived payload read from a local file or command, identified by
+// method (MethodFile or MethodExec) and uri, served in the same order as RoundTrip
+func (r *Replay) Payload(method string, uri string) ([]byte, error) {
+
+	entry, err := r.next(method, uri)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Body(entry)
+
+}
+
+// next returns the next archived entry for a request, repeating the last once all are served
+func (r *Replay) next(method string, uri string) (Entry, error) {
+
+	key := method + " " + uri
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := r.entries[key]
+	if len(entries) == 0 {
+		return Entry{}, fmt.Errorf("no archived response for %s", key)
+	}
+	entry := entries[len(entries)-1]
+	if r.served[key] < len(entries) {
+		entry = entries[r.served[key]]
+		r.served[key]++
+	}
+
+	return entry, nil
+
+}
+
+// Page returns the archived response recorded with sequence seq
+func (r *Replay) Page(seq int) (Page, error) {
+
+	for _, entries := range r.entries {
+		for _, entry := range entries {
+			if entry.Seq == seq {
+				return r.page(entry)
+			}
+		}
+	}
+
+	return Page{}, fmt.Errorf("no archived response with sequence %d in run %s", seq, r.Manifest.RunID)
+
+}
+
+// Entries returns every archived entry within the run, in fetch order
+func (r *Replay) Entries() []Entry {
+
+	var entries []Entry
+	for _, byRequest := range r.entries {
+		entries = append(entries, byRequest...)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Seq < entries[j].Seq })
+
+	return entries
+
+}
+
+// page builds a servable Page from an archived entry
+func (r *Replay) page(entry Entry) (Page, error) {
+
+	body, err := r.Body(entry)
+	if err != nil {
+		return Page{}, err
+	}
+
+	return Page{Status: entry.Status, Header: entry.Headers, Body: body}, nil
+
+}
+
+// Body reads the archived body for an entry
+func (r *Replay) Body(entry Entry) ([]byte, error) {
+
+	if len(entry.Body) < 2 {
+		return nil, fmt.Errorf("archived entry %d has no body reference", entry.Seq)
+	}
+
+	return ioutil.ReadFile(filepath.Join(r.dir, "objects", entry.Body[:2], entry.Body))
+
+}
+
+// Page is a single saved response, served for every request made through it.
+// Used to parse saved pages and archived responses without network access.
+type Page struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// ReadPage reads a saved page from a file, its content type is detected from the
+// file extension or contents
+func ReadPage(path string) (Page, error) {
+
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Page{}, err
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+
+	return Page{Status: http.StatusOK, Header: http.Header{"Content-Type": {contentType}}, Body: body}, nil
+
+}
+
+// RoundTrip serves the page in response to req
+func (p Page) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	header := make(http.Header, len(p.Header))
+	for name, values := range p.Header {
+		header[name] = values
+	}
+	// Saved bodies are stored decoded
+	header.Del("Content-Encoding")
+	header.Set("Content-Length", strconv.Itoa(len(p.Body)))
+
+	status := p.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(p.Body)),
+		ContentLength: int64(len(p.Body)),
+		Request:       req,
+	}, nil
+
+}
@@ -0,0 +1,108 @@
+/*
+   Domain types shared between sources and the target, so predictions keep their
+   fixture identity from source retrieval through to submission.
+
+   Teams are always canonical team IDs (see internal/teams).
+*/
+
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Draw is recorded as the winner of a drawn (or predicted to be drawn) fixture
+const Draw = "draw"
+
+// Round holds a round ID along with fixtures, predictions and/or results within the round
+type Round struct {
+	ID          int          // Round ID, determined by date
+	Fixtures    []Fixture    // Fixtures (matches) within the round
+	Predictions []Prediction // Predictions for fixtures within the round
+	Results     []Result     // Results of completed fixtures within the round
+}
+
+// Fixture is a match between two teams within a tournament
+type Fixture struct {
+	LeftTeam   string // teamA, canonical team ID
+	RightTeam  string // teamB, canonical team ID
+	Tournament string // Tournament the fixture belongs to, empty when unknown
+}
+
+// Prediction is a predicted outcome for a fixture
+type Prediction struct {
+	Fixture
+	Winner string // Canonical team ID of the predicted winner, or Draw
+	Margin int    // Point difference for the winning team, 0 for a draw
+}
+
+// Result is the outcome of a completed fixture
+type Result struct {
+	Fixture
+	Winner string // Canonical team ID of the winner, or Draw
+	Margin int    // Point difference for the winning team, 0 for a draw
+}
+
+// String formats a fixture as "leftTeam v rightTeam"
+func (f Fixture) String() string {
+	return fmt.Sprintf("%s v %s", f.LeftTeam, f.RightTeam)
+}
+
+// Matches reports whether other is the same pair of teams, in either order, within the same
+// tournament. Tournaments are compared case insensitively and only when known for both fixtures.
+func (f Fixture) Matches(other Fixture) bool {
+	return f.SameTournament(other) &&
+		((f.LeftTeam == other.LeftTeam && f.RightTeam == other.RightTeam) ||
+			(f.LeftTeam == other.RightTeam && f.RightTeam == other.LeftTeam))
+}
+
+// SameTournament reports whether other belongs to the same tournament,
+// fixtures with an unknown tournament are assumed to
+func (f Fixture) SameTournament(other Fixture) bool {
+	return f.Tournament == "" || other.Tournament == "" || strings.EqualFold(f.Tournament, other.Tournament)
+}
+
+// Reversed reports whether other is the same pair of teams, in the opposite order
+func (f Fixture) Reversed(other Fixture) bool {
+	return f.LeftTeam == other.RightTeam && f.RightTeam == other.LeftTeam && f.LeftTeam != f.RightTeam
+}
+
+// NewPrediction builds a prediction from a signed margin (left minus right),
+// a negative margin means the right team wins and 0 is a draw
+func NewPrediction(f Fixture, signedMargin int) Prediction {
+
+	switch {
+	case signedMargin > 0:
+		return Prediction{Fixture: f, Winner: f.LeftTeam, Margin: signedMargin}
+	case signedMargin < 0:
+		return Prediction{Fixture: f, Winner: f.RightTeam, Margin: -signedMargin}
+	default:
+		return Prediction{Fixture: f, Winner: Draw}
+	}
+
+}
+
+// Signed returns the predicted margin from the left teams perspective (left minus right)
+func (p Prediction) Signed() int {
+	return signed(p.Fixture, p.Winner, p.Margin)
+}
+
+// Signed returns the result margin from the left teams perspective (left minus right)
+func (r Result) Signed() int {
+	return signed(r.Fixture, r.Winner, r.Margin)
+}
+
+// signed converts a winner and margin to a margin from the left teams perspective
+func signed(f Fixture, winner string, margin int) int {
+
+	switch winner {
+	case f.LeftTeam:
+		return margin
+	case f.RightTeam:
+		return -margin
+	default:
+		return 0
+	}
+
+}
@@ -0,0 +1,63 @@
+package domain
+
+import "fmt"
+
+// Drift policies, applied when a parser health check fails
+const (
+	DriftFail    = "fail"    // The run fails
+	DriftDegrade = "degrade" // The drifted source or page is dropped and the run continues where policy allows
+)
+
+// Parser health checks
+const (
+	CheckMatch        = "match"         // A selector matched nothing
+	CheckTeamName     = "team name"     // A team name was empty
+	CheckKnownTeam    = "known team"    // A team name did not resolve to a canonical team
+	CheckMargin       = "margin"        // A margin was not numeric
+	CheckTeamID       = "team ID"       // A target team ID was not numeric
+	CheckFixtureCount = "fixture count" // Fewer fixtures were parsed than the target has
+)
+
+// ParserDriftError is returned when a parser health check fails, typically because
+// a site has changed its markup and a selector no longer matches what it used to
+type ParserDriftError struct {
+	Page     string // Source name or target page (fixtures, results)
+	Key      string // Parser key of the failing selector (i.e. attr_t_iterator)
+	Selector string // Configured selector or path for Key
+	Check    string // Failed health check
+	Detail   string // Optional detail, i.e. counts
+	Err      error  // Optional underlying error
+}
+
+func (e *ParserDriftError) Error() string {
+
+	msg := fmt.Sprintf("Parser drift on %s: %s (%s) failed %s check", e.Page, e.Key, e.Selector, e.Check)
+	if e.Detail != "" {
+		msg += ", " + e.Detail
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+
+	return msg
+
+}
+
+// Unwrap returns the underlying error
+func (e *ParserDriftError) Unwrap() error {
+	return e.Err
+}
+
+// DriftPolicy validates a drift policy, defaulting to DriftDegrade
+func DriftPolicy(policy string) (string, error) {
+
+	switch policy {
+	case "", DriftDegrade:
+		return DriftDegrade, nil
+	case DriftFail:
+		return DriftFail, nil
+	}
+
+	return "", fmt.Errorf("Unknown drift policy: %q", policy)
+
+}
@@ -8,23 +8,24 @@ import (
 	_ "github.com/lib/pq"
 )
 
-// ConfigInit invokes reading and parsing of config file parameters
-func ConfigInit() (config.GlobalConfig, config.TargetConfig, config.SourcesConfig, error) {
+// ConfigInit invokes reading and parsing of config file parameters,
+// returning config for every configured target
+func ConfigInit() (config.GlobalConfig, []config.TargetConfig, config.SourcesConfig, error) {
 
 	bruConfig := new(config.Parameters)
 	globalConfig := new(config.GlobalConfig)
-	targetConfig := new(config.TargetConfig)
+	targetsConfig := new([]config.TargetConfig)
 	sourcesConfig := new(config.SourcesConfig)
 
 	if err := bruConfig.Init(); err != nil {
-		return *globalConfig, *targetConfig, *sourcesConfig, err
+		return *globalConfig, *targetsConfig, *sourcesConfig, err
 	}
 
-	if err := bruConfig.ParseConfig(globalConfig, targetConfig, sourcesConfig); err != nil {
-		return *globalConfig, *targetConfig, *sourcesConfig, err
+	if err := bruConfig.ParseConfig(globalConfig, targetsConfig, sourcesConfig); err != nil {
+		return *globalConfig, *targetsConfig, *sourcesConfig, err
 	}
 
-	return *globalConfig, *targetConfig, *sourcesConfig, nil
+	return *globalConfig, *targetsConfig, *sourcesConfig, nil
 }
 
 // DBInit initialises database connectivity
@@ -0,0 +1,110 @@
+package helpers
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JSONPath resolves a dot separated path expression against decoded JSON (as returned by
+// json.Unmarshal into an interface{}), i.e. "data.rounds.0.fixtures" or "data.rounds[0].fixtures".
+// Array elements are addressed by index, an empty path or "$" resolves to data itself.
+//
+// A wildcard (* or [*]) resolves the rest of the path against every array element (or object
+// value, ordered by key), i.e. "data.rounds[*].id", returning an array of what resolved.
+// Elements the rest of the path does not resolve within are skipped.
+func JSONPath(data interface{}, path string) (interface{}, error) {
+
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+
+	if path == "" {
+		return data, nil
+	}
+
+	return resolvePath(data, strings.Split(path, "."), path)
+
+}
+
+// resolvePath resolves keys against current, path is the whole expression for errors
+func resolvePath(current interface{}, keys []string, path string) (interface{}, error) {
+
+	for k, key := range keys {
+
+		if key == "*" {
+			var elements []interface{}
+			switch node := current.(type) {
+			case []interface{}:
+				elements = node
+			case map[string]interface{}:
+				names := make([]string, 0, len(node))
+				for name := range node {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				for _, name := range names {
+					elements = append(elements, node[name])
+				}
+			default:
+				return nil, fmt.Errorf("path %s: cannot resolve %s within %T", path, key, current)
+			}
+
+			matched := make([]interface{}, 0, len(elements))
+			for _, element := range elements {
+				if val, err := resolvePath(element, keys[k+1:], path); err == nil {
+					matched = append(matched, val)
+				}
+			}
+			return matched, nil
+		}
+
+		switch node := current.(type) {
+		case map[string]interface{}:
+			val, ok := node[key]
+			if !ok {
+				return nil, fmt.Errorf("path %s: key %s not found", path, key)
+			}
+			current = val
+		case []interface{}:
+			idx, err := strconv.Atoi(key)
+			if err != nil {
+				return nil, fmt.Errorf("path %s: %s is not an array index", path, key)
+			}
+			if idx < 0 || idx >= len(node) {
+				return nil, fmt.Errorf("path %s: index %d out of range", path, idx)
+			}
+			current = node[idx]
+		default:
+			return nil, fmt.Errorf("path %s: cannot resolve %s within %T", path, key, current)
+		}
+
+	}
+
+	return current, nil
+
+}
+
+// JSONPathString resolves path against data, returning the value formatted as a string.
+// Numbers are formatted without trailing zeros and a null value resolves to an empty string.
+func JSONPathString(data interface{}, path string) (string, error) {
+
+	val, err := JSONPath(data, path)
+	if err != nil {
+		return "", err
+	}
+
+	switch v := val.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	default:
+		return "", fmt.Errorf("path %s: %T is not a scalar value", path, val)
+	}
+
+}
@@ -0,0 +1,110 @@
+package helpers
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const testDocument = `{
+	"data": {
+		"round": 12,
+		"live": false,
+		"rounds": [
+			{"id": 1, "games": [{"home": "Blues", "away": "Chiefs", "margin": 7}]},
+			{"id": 2, "games": [{"home": "Crusaders", "away": "Reds", "margin": -3.5}, {"home": "Brumbies"}]}
+		],
+		"venues": {"b": {"city": "Auckland"}, "a": {"city": "Hamilton"}, "c": {}},
+		"note": null
+	}
+}`
+
+func TestJSONPath(t *testing.T) {
+
+	var data interface{}
+	if err := json.Unmarshal([]byte(testDocument), &data); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		want    interface{}
+		wantErr bool
+	}{
+		{"nested keys", "data.round", 12.0, false},
+		{"root prefix", "$.data.round", 12.0, false},
+		{"index by dot", "data.rounds.1.id", 2.0, false},
+		{"index by bracket", "data.rounds[1].games[0].home", "Crusaders", false},
+		{"null value", "data.note", nil, false},
+		{"wildcard array", "data.rounds[*].id", []interface{}{1.0, 2.0}, false},
+		{"wildcard by dot", "data.rounds.*.id", []interface{}{1.0, 2.0}, false},
+		{"wildcard nested", "data.rounds[*].games[*].home", []interface{}{
+			[]interface{}{"Blues"},
+			[]interface{}{"Crusaders", "Brumbies"},
+		}, false},
+		{"wildcard skips missing", "data.rounds[1].games[*].away", []interface{}{"Reds"}, false},
+		{"wildcard object ordered by key", "data.venues.*.city", []interface{}{"Hamilton", "Auckland"}, false},
+		{"wildcard trailing", "data.rounds[0].games[0].*", []interface{}{"Chiefs", "Blues", 7.0}, false},
+		{"wildcard nothing matched", "data.rounds[*].missing", []interface{}{}, false},
+		{"missing key", "data.fixtures", nil, true},
+		{"missing nested key", "data.rounds.0.fixtures", nil, true},
+		{"index out of range", "data.rounds[2]", nil, true},
+		{"negative index", "data.rounds[-1]", nil, true},
+		{"key within array", "data.rounds.first", nil, true},
+		{"key within scalar", "data.round.id", nil, true},
+		{"wildcard within scalar", "data.round[*]", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := JSONPath(data, tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("JSONPath(%q) err = %v, want error %v", tt.path, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("JSONPath(%q) = %#v, want %#v", tt.path, got, tt.want)
+			}
+		})
+	}
+
+	// An empty path resolves to the document itself
+	if got, err := JSONPath(data, ""); err != nil || !reflect.DeepEqual(got, data) {
+		t.Errorf("JSONPath(\"\") = %v, %v, want the document", got, err)
+	}
+
+}
+
+func TestJSONPathString(t *testing.T) {
+
+	var data interface{}
+	if err := json.Unmarshal([]byte(testDocument), &data); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{"data.rounds[0].games[0].home", "Blues", false},
+		{"data.round", "12", false},
+		{"data.rounds[1].games[0].margin", "-3.5", false},
+		{"data.live", "false", false},
+		{"data.note", "", false},
+		{"data.rounds", "", true},
+		{"data.missing", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := JSONPathString(data, tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("JSONPathString(%q) err = %v, want error %v", tt.path, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("JSONPathString(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+}
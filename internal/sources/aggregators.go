@@ -0,0 +1,183 @@
+package sources
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Built in aggregation strategies, selected by sources.aggregation.strategy
+const (
+	WeightedMean   = "weighted_mean"   // weighted mean of all signed margins (default)
+	WeightedMedian = "weighted_median" // weighted median of all signed margins
+	TrimmedMean    = "trimmed_mean"    // weighted mean after trimming the most extreme margins
+	MajorityMean   = "majority_mean"   // majority winner by weight, then weighted mean among agreeing sources
+)
+
+// Aggregator combines every sources contribution to a fixture into a single signed margin
+// (left minus right), from which the fixtures winner and margin are derived
+type Aggregator interface {
+	Name() string
+	Aggregate(contributions []Contribution) float64
+}
+
+// NewAggregator returns the built in aggregation strategy for name, trim is the fraction
+// of contributions dropped from each end by the trimmed mean (i.e. 0.1)
+func NewAggregator(name string, trim float64) (Aggregator, error) {
+
+	switch name {
+	case WeightedMean, "":
+		return weightedMean{}, nil
+	case WeightedMedian:
+		return weightedMedian{}, nil
+	case TrimmedMean:
+		if trim < 0 || trim >= 0.5 {
+			return nil, fmt.Errorf("trimmed mean requires a trim fraction between 0 and 0.5, got: %.2f", trim)
+		}
+		return trimmedMean{trim: trim}, nil
+	case MajorityMean:
+		return majorityMean{}, nil
+	default:
+		return nil, fmt.Errorf("unknown aggregation strategy: %q", name)
+	}
+
+}
+
+// weightedMean is the mean of signed margins, normalised by the sum of contributing weights
+type weightedMean struct{}
+
+func (weightedMean) Name() string { return WeightedMean }
+
+func (weightedMean) Aggregate(contributions []Contribution) float64 {
+
+	var weightedSum, totalWeight float64
+
+	for _, c := range contributions {
+		weightedSum += float64(c.SignedMargin) * c.Weight
+		totalWeight += c.Weight
+	}
+
+	if totalWeight == 0 {
+		return 0
+	}
+
+	return weightedSum / totalWeight
+
+}
+
+// weightedMedian is the signed margin at which half of the total weight lies either side
+type weightedMedian struct{}
+
+func (weightedMedian) Name() string { return WeightedMedian }
+
+func (weightedMedian) Aggregate(contributions []Contribution) float64 {
+
+	var totalWeight, cumulative float64
+
+	sorted := sortedContributions(contributions)
+	for _, c := range sorted {
+		totalWeight += c.Weight
+	}
+
+	for idx, c := range sorted {
+		cumulative += c.Weight
+		if cumulative > totalWeight/2 {
+			return float64(c.SignedMargin)
+		}
+		// Exactly half of the weight is either side, split the difference
+		if cumulative == totalWeight/2 && idx+1 < len(sorted) {
+			return float64(c.SignedMargin+sorted[idx+1].SignedMargin) / 2
+		}
+	}
+
+	return 0
+
+}
+
+// trimmedMean drops the trim fraction of contributions from each end of the sorted
+// signed margins before taking the weighted mean of what remains
+type trimmedMean struct {
+	trim float64
+}
+
+func (trimmedMean) Name() string { return TrimmedMean }
+
+func (t trimmedMean) Aggregate(contributions []Contribution) float64 {
+
+	sorted := sortedContributions(contributions)
+	drop := int(float64(len(sorted)) * t.trim)
+
+	// Too few contributions to trim anything sensibly
+	if len(sorted)-2*drop <= 0 {
+		return weightedMean{}.Aggregate(sorted)
+	}
+
+	return weightedMean{}.Aggregate(sorted[drop : len(sorted)-drop])
+
+}
+
+// majorityMean picks the winner (left, right or draw) backed by the most weight,
+// then takes the weighted mean margin among only the sources agreeing on that winner
+type majorityMean struct{}
+
+func (majorityMean) Name() string { return MajorityMean }
+
+func (majorityMean) Aggregate(contributions []Contribution) float64 {
+
+	// Weight behind each outcome keyed by the sign of the margin
+	votes := make(map[int]float64)
+	for _, c := range contributions {
+		votes[sign(c.SignedMargin)] += c.Weight
+	}
+
+	majority, best, tied := 0, -1.0, false
+	for outcome, weight := range votes {
+		switch {
+		case weight > best:
+			majority, best, tied = outcome, weight, false
+		case weight == best:
+			tied = true
+		}
+	}
+
+	// No clear majority, fall back to every source
+	if tied {
+		return weightedMean{}.Aggregate(contributions)
+	}
+
+	var agreeing []Contribution
+	for _, c := range contributions {
+		if sign(c.SignedMargin) == majority {
+			agreeing = append(agreeing, c)
+		}
+	}
+
+	return weightedMean{}.Aggregate(agreeing)
+
+}
+
+// sortedContributions returns a copy of contributions ordered by signed margin
+func sortedContributions(contributions []Contribution) []Contribution {
+
+	sorted := make([]Contribution, len(contributions))
+	copy(sorted, contributions)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].SignedMargin < sorted[j].SignedMargin
+	})
+
+	return sorted
+
+}
+
+// sign returns -1, 0 or 1 for a signed margin
+func sign(margin int) int {
+
+	switch {
+	case margin > 0:
+		return 1
+	case margin < 0:
+		return -1
+	default:
+		return 0
+	}
+
+}
@@ -0,0 +1,85 @@
+package sources
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAggregators(t *testing.T) {
+
+	c := func(margin int, weight float64) Contribution {
+		return Contribution{SignedMargin: margin, Weight: weight}
+	}
+
+	tests := []struct {
+		name          string
+		strategy      string
+		trim          float64
+		contributions []Contribution
+		want          float64
+	}{
+		{"mean single source", WeightedMean, 0, []Contribution{c(10, 0.3)}, 10},
+		{"mean weighted", WeightedMean, 0, []Contribution{c(10, 1), c(-2, 3)}, 1},
+		{"mean tie is a draw", WeightedMean, 0, []Contribution{c(6, 1), c(-6, 1)}, 0},
+		{"mean no weight", WeightedMean, 0, []Contribution{c(6, 0)}, 0},
+		{"mean no contributions", WeightedMean, 0, nil, 0},
+
+		{"median single source", WeightedMedian, 0, []Contribution{c(10, 0.3)}, 10},
+		{"median odd", WeightedMedian, 0, []Contribution{c(9, 1), c(1, 1), c(5, 1)}, 5},
+		{"median weighted", WeightedMedian, 0, []Contribution{c(20, 1), c(8, 1), c(2, 3)}, 2},
+		// Exactly half the weight lies either side, the difference is split
+		{"median tie splits", WeightedMedian, 0, []Contribution{c(8, 1), c(2, 1)}, 5},
+		{"median tie across a draw", WeightedMedian, 0, []Contribution{c(-4, 2), c(4, 2)}, 0},
+
+		{"trimmed single source", TrimmedMean, 0.2, []Contribution{c(10, 1)}, 10},
+		{"trimmed outliers", TrimmedMean, 0.2, []Contribution{c(40, 1), c(2, 1), c(-30, 1), c(6, 1), c(4, 1)}, 4},
+		{"trimmed quarter", TrimmedMean, 0.25, []Contribution{c(1, 1), c(3, 1), c(5, 3), c(100, 1)}, 4.5},
+		{"trimmed too few to trim", TrimmedMean, 0.4, []Contribution{c(10, 1), c(2, 1)}, 6},
+		{"trimmed nothing", TrimmedMean, 0, []Contribution{c(10, 1), c(2, 3)}, 4},
+
+		{"majority single source", MajorityMean, 0, []Contribution{c(-7, 0.5)}, -7},
+		{"majority by count", MajorityMean, 0, []Contribution{c(10, 1), c(6, 1), c(-20, 1)}, 8},
+		{"majority by weight", MajorityMean, 0, []Contribution{c(10, 1), c(6, 1), c(-4, 3)}, -4},
+		{"majority draw", MajorityMean, 0, []Contribution{c(0, 2), c(6, 1)}, 0},
+		// No clear majority falls back to the mean of every source
+		{"majority tie", MajorityMean, 0, []Contribution{c(10, 1), c(-4, 1)}, 3},
+		{"majority three way tie", MajorityMean, 0, []Contribution{c(9, 1), c(-3, 1), c(0, 1)}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			aggregator, err := NewAggregator(tt.strategy, tt.trim)
+			if err != nil {
+				t.Fatalf("NewAggregator(%q, %.2f): %v", tt.strategy, tt.trim, err)
+			}
+			if aggregator.Name() != tt.strategy {
+				t.Errorf("Name() = %q, want %q", aggregator.Name(), tt.strategy)
+			}
+			if got := aggregator.Aggregate(tt.contributions); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Aggregate() = %v, want %v", got, tt.want)
+			}
+
+		})
+	}
+
+}
+
+func TestNewAggregatorInvalid(t *testing.T) {
+
+	tests := []struct {
+		strategy string
+		trim     float64
+	}{
+		{TrimmedMean, 0.5},
+		{TrimmedMean, -0.1},
+		{"mode", 0},
+	}
+
+	for _, tt := range tests {
+		if _, err := NewAggregator(tt.strategy, tt.trim); err == nil {
+			t.Errorf("NewAggregator(%q, %.2f) succeeded, want an error", tt.strategy, tt.trim)
+		}
+	}
+
+}
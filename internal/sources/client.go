@@ -13,6 +13,7 @@
 package sources
 
 import (
+	"context"
 	"net"
 	"net/http"
 	"time"
@@ -28,6 +29,7 @@ type client struct {
 	collector *colly.Collector
 	config    clientConfig
 	parser    clientParser
+	transport http.RoundTripper // base transport, wrapped per-run to bind requests to a context
 }
 
 // Client setup for each source endpoint
@@ -62,12 +64,44 @@ func (c *client) init() {
 		)
 	}
 
-	c.collector.WithTransport(&http.Transport{
+	c.transport = &http.Transport{
 		DialContext: (&net.Dialer{
 			Timeout: time.Second * c.config.dialTimeout,
 		}).DialContext,
 		TLSHandshakeTimeout: time.Second * c.config.tlsHandShakeTimeout,
-	})
+	}
+	c.collector.WithTransport(c.transport)
 
 	c.collector.IgnoreRobotsTxt = c.config.ignoreRobots
 }
+
+// replay re-initialises the client to serve responses from transport (an archived run),
+// bypassing the cache and robots.txt which are not archived
+func (c *client) replay(transport http.RoundTripper) {
+
+	c.config.enableCache = false
+	c.config.ignoreRobots = true
+	c.init()
+
+	c.transport = transport
+	c.collector.WithTransport(c.transport)
+
+}
+
+// withContext binds all subsequent collector requests to ctx, colly (v2.0.x)
+// has no notion of a context.Context so this happens at the transport
+func (c *client) withContext(ctx context.Context) {
+	c.collector.WithTransport(contextTransport{ctx: ctx, base: c.transport})
+}
+
+// contextTransport attaches a context to every request passing through it,
+// cancelling in-flight requests once a sources deadline has passed
+type contextTransport struct {
+	ctx  context.Context
+	base http.RoundTripper
+}
+
+// RoundTrip executes a single HTTP transaction bound to the transports context
+func (t contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.base.RoundTrip(req.WithContext(t.ctx))
+}
@@ -0,0 +1,209 @@
+package sources
+
+import (
+	"brubot/internal/archive"
+	"brubot/internal/helpers"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register("exec", execProvider{defaults: structuredDefaults})
+}
+
+// execProvider runs a configured command (i.e. an in-house model script) and parses
+// its stdout as JSON, fields are mapped onto fixtures in the same way as the json provider,
+// with the following additional client.parser.predictions:
+//
+//	exec_command: command and arguments, split on whitespace unless quoted (shell style), arguments may contain
+//	              %d for the (source) round ID. The command is not run by a shell.
+//	exec_timeout: seconds to wait for the command to complete, defaults to 60
+//
+// The round ID is also passed to the command within the BRUBOT_ROUND_ID environment variable.
+type execProvider struct {
+	defaults map[string]string
+}
+
+// Iterator returns the path iterating fixtures
+func (x execProvider) Iterator(s *Source) (string, string) {
+	return "path_iterator", parserOptions(x.defaults, s)["path_iterator"]
+}
+
+// Validate confirms a sources parser configuration can be used by the exec provider
+func (x execProvider) Validate(s *Source) error {
+
+	options := parserOptions(x.defaults, s)
+
+	if _, err := execArgs(options); err != nil {
+		return err
+	}
+	if _, err := execTimeout(options); err != nil {
+		return err
+	}
+	if _, err := newJSONParser(options); err != nil {
+		return err
+	}
+
+	return nil
+
+}
+
+// Predictions runs the command for an exec source and parses predicted margins from its output
+func (x execProvider) Predictions(ctx context.Context, s *Source) error {
+
+	options := parserOptions(x.defaults, s)
+
+	p, err := newJSONParser(options)
+	if err != nil {
+		return err
+	}
+
+	timeout, err := execTimeout(options)
+	if err != nil {
+		return err
+	}
+
+	roundID := s.sourceRound()
+
+	args, err := execArgs(options)
+	if err != nil {
+		return err
+	}
+	for idx := range args {
+		args[idx] = formatRound(args[idx], roundID)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	output, err := s.payload(archive.MethodExec, strings.Join(args, " "), func() ([]byte, error) {
+
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+		cmd.Env = append(os.Environ(), fmt.Sprintf("BRUBOT_ROUND_ID=%d", roundID))
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+
+		helpers.Logger.Debugf("Prediction retrieval from command %v", args)
+
+		if err := cmd.Run(); err != nil {
+			return nil, fmt.Errorf("command %s failed: %w, stderr: %s", args[0], err, strings.TrimSpace(stderr.String()))
+		}
+
+		return stdout.Bytes(), nil
+
+	})
+	if err != nil {
+		return err
+	}
+
+	body, err := decodeStructured("json", output)
+	if err != nil {
+		return fmt.Errorf("failed decoding output of command %s: %w", args[0], err)
+	}
+
+	s.matches = p.matches(body)
+	fixtures, err := p.fixtures(body, s.team)
+	s.Round.Predictions = append(s.Round.Predictions, fixtures...)
+
+	return err
+
+}
+
+// execTimeout parses the optional exec_timeout parser option (seconds)
+func execTimeout(options map[string]string) (time.Duration, error) {
+
+	if options["exec_timeout"] == "" {
+		return time.Second * 60, nil
+	}
+
+	timeout, err := strconv.Atoi(options["exec_timeout"])
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("invalid exec_timeout: %q", options["exec_timeout"])
+	}
+
+	return time.Second * time.Duration(timeout), nil
+
+}
+
+// execArgs splits exec_command into the command and its arguments
+func execArgs(options map[string]string) ([]string, error) {
+
+	args, err := splitCommand(options["exec_command"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid exec_command: %w", err)
+	}
+	if len(args) == 0 {
+		return nil, errors.New("exec source requires exec_command")
+	}
+
+	return args, nil
+
+}
+
+// splitCommand splits command into arguments on whitespace as a shell would. Single quotes
+// preserve everything within them, double quotes preserve everything but backslash escapes
+// (of \, " and $) and a backslash outside quotes escapes the next character. Nothing is expanded.
+func splitCommand(command string) ([]string, error) {
+
+	var args []string
+	var arg strings.Builder
+	var quote rune
+	inArg, escaped := false, false
+
+	for _, r := range command {
+		switch {
+		case escaped:
+			if quote == '"' && r != '\\' && r != '"' && r != '$' {
+				arg.WriteRune('\\')
+			}
+			arg.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inArg = true, true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if escaped {
+		return nil, errors.New("trailing backslash")
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+
+	return args, nil
+
+}
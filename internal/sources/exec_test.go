@@ -0,0 +1,40 @@
+package sources
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitCommand(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		command string
+		want    []string
+		wantErr bool
+	}{
+		{"whitespace", "python3  model.py\t--round %d", []string{"python3", "model.py", "--round", "%d"}, false},
+		{"single quoted path", "python3 '/opt/our model/model.py' --round %d", []string{"python3", "/opt/our model/model.py", "--round", "%d"}, false},
+		{"double quoted", `run "a \"b\" \$c \d"`, []string{"run", `a "b" $c \d`}, false},
+		{"single quotes keep backslashes", `run 'a\b'`, []string{"run", `a\b`}, false},
+		{"escaped space", `/opt/our\ model/run`, []string{"/opt/our model/run"}, false},
+		{"adjacent quotes join", `a"b c"'d'`, []string{"ab cd"}, false},
+		{"empty argument", `run "" x`, []string{"run", "", "x"}, false},
+		{"empty", "   ", nil, false},
+		{"unterminated quote", `run "model`, nil, true},
+		{"trailing backslash", `run \`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitCommand(tt.command)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("splitCommand(%q) err = %v, want error %v", tt.command, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitCommand(%q) = %q, want %q", tt.command, got, tt.want)
+			}
+		})
+	}
+
+}
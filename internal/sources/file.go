@@ -0,0 +1,216 @@
+package sources
+
+import (
+	"brubot/internal/archive"
+	"brubot/internal/helpers"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+func init() {
+	Register("file", fileProvider{defaults: structuredDefaults})
+}
+
+// structuredDefaults map fixture fields for sources read from local files or commands,
+// matching column names for CSV files and keys for JSON and YAML documents
+var structuredDefaults = map[string]string{
+	"path_leftteam":  "leftteam",
+	"path_rightteam": "rightteam",
+	"path_margin":    "margin",
+}
+
+// fileProvider reads predictions from a local CSV, JSON or YAML file, i.e. margins produced
+// by in-house models. Fields are mapped onto fixtures in the same way as the json provider,
+// with the following additional client.parser.predictions:
+//
+//	file_path:   path to the predictions file, may contain %d for the (source) round ID
+//	file_format: csv, json or yaml, defaults to the file_path extension
+//
+// CSV files require a header row, each row is treated as an object keyed by column name
+// so path_leftteam, path_rightteam, path_margin and path_winner refer to column names.
+// path_leftteam, path_rightteam and path_margin default to leftteam, rightteam and margin.
+type fileProvider struct {
+	defaults map[string]string
+}
+
+// Iterator returns the path iterating fixtures
+func (f fileProvider) Iterator(s *Source) (string, string) {
+	return "path_iterator", parserOptions(f.defaults, s)["path_iterator"]
+}
+
+// Validate confirms a sources parser configuration can be used by the file provider
+func (f fileProvider) Validate(s *Source) error {
+
+	options := parserOptions(f.defaults, s)
+
+	if options["file_path"] == "" {
+		return errors.New("file source requires file_path")
+	}
+	if _, err := fileFormat(options); err != nil {
+		return err
+	}
+	if _, err := newJSONParser(options); err != nil {
+		return err
+	}
+
+	return nil
+
+}
+
+// Predictions reads predicted margins for a file source
+func (f fileProvider) Predictions(ctx context.Context, s *Source) error {
+
+	options := parserOptions(f.defaults, s)
+
+	p, err := newJSONParser(options)
+	if err != nil {
+		return err
+	}
+
+	format, err := fileFormat(options)
+	if err != nil {
+		return err
+	}
+
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	path := formatRound(options["file_path"], s.sourceRound())
+	helpers.Logger.Debugf("Prediction retrieval from %s", path)
+
+	data, err := s.payload(archive.MethodFile, path, func() ([]byte, error) {
+		return ioutil.ReadFile(path)
+	})
+	if err != nil {
+		return err
+	}
+
+	body, err := decodeStructured(format, data)
+	if err != nil {
+		return fmt.Errorf("failed decoding %s: %w", path, err)
+	}
+
+	s.matches = p.matches(body)
+	fixtures, err := p.fixtures(body, s.team)
+	s.Round.Predictions = append(s.Round.Predictions, fixtures...)
+
+	return err
+
+}
+
+// fileFormat returns the configured file_format, falling back to the file_path extension
+func fileFormat(options map[string]string) (string, error) {
+
+	format := strings.ToLower(options["file_format"])
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(options["file_path"])), ".")
+	}
+
+	switch format {
+	case "csv", "json":
+		return format, nil
+	case "yaml", "yml":
+		return "yaml", nil
+	default:
+		return "", fmt.Errorf("unsupported file_format: %q", format)
+	}
+
+}
+
+// decodeStructured decodes CSV, JSON or YAML into the same generic structure
+// produced by json.Unmarshal, ready for mapping with helpers.JSONPath
+func decodeStructured(format string, data []byte) (interface{}, error) {
+
+	var body interface{}
+
+	switch format {
+	case "json":
+		if err := json.Unmarshal(data, &body); err != nil {
+			return nil, err
+		}
+	case "yaml":
+		if err := yaml.Unmarshal(data, &body); err != nil {
+			return nil, err
+		}
+		body = normaliseYAML(body)
+	case "csv":
+		return decodeCSV(data)
+	default:
+		return nil, fmt.Errorf("unsupported format: %q", format)
+	}
+
+	return body, nil
+
+}
+
+// decodeCSV decodes CSV with a header row into a slice of rows keyed by column name
+func decodeCSV(data []byte) (interface{}, error) {
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed reading CSV header: %w", err)
+	}
+	for idx := range header {
+		header[idx] = strings.TrimSpace(header[idx])
+	}
+
+	rows := []interface{}{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]interface{})
+		for idx := range record {
+			if idx < len(header) {
+				row[header[idx]] = strings.TrimSpace(record[idx])
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+
+}
+
+// normaliseYAML converts YAML decoded maps and numbers into their json.Unmarshal
+// equivalents (map[string]interface{} and float64)
+func normaliseYAML(node interface{}) interface{} {
+
+	switch v := node.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			m[fmt.Sprint(key)] = normaliseYAML(val)
+		}
+		return m
+	case []interface{}:
+		for idx := range v {
+			v[idx] = normaliseYAML(v[idx])
+		}
+		return v
+	case int:
+		return float64(v)
+	default:
+		return v
+	}
+
+}
@@ -0,0 +1,298 @@
+package sources
+
+import (
+	"brubot/internal/domain"
+	"brubot/internal/helpers"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+)
+
+func init() {
+	Register("html", htmlProvider{})
+}
+
+// Margin sign conventions supported by html sources
+const (
+	marginSigned = "signed" // single margin cell, a negative margin means the right team wins
+	marginSplit  = "split"  // separate left and right margin cells, the populated cell wins
+)
+
+// htmlProvider is a generic, declarative provider for sources scraped from html,
+// all behaviour is driven by client.parser.predictions:
+//
+//	attr_onhtml:        selector for the element holding all fixtures, may contain %d for the (source) round ID
+//	attr_t_iterator:    selector for each fixture within attr_onhtml
+//	attr_t_leftteam:    selector for the left team name within a fixture
+//	attr_t_rightteam:   selector for the right team name within a fixture
+//	attr_t_margin:      selector for a signed margin (margin_sign: signed)
+//	attr_t_leftmargin:  selector for the left teams margin (margin_sign: split)
+//	attr_t_rightmargin: selector for the right teams margin (margin_sign: split)
+//	margin_regex:       optional regex used to extract the margin from the margin cell text
+//	margin_sign:        signed (default) or split
+//	round_offset:       optional offset added to the round ID, used where the sources rounds.offset is not set
+//
+// The predictions url (client.urls.predictions) may also contain %d for the round ID.
+// defaults are used where a parser key is not set in config, allowing existing
+// sources to be described as presets of the html provider.
+type htmlProvider struct {
+	defaults map[string]string
+}
+
+// Defaults returns the providers default parser options
+func (h htmlProvider) Defaults() map[string]string {
+	return h.defaults
+}
+
+// htmlParser holds the parsed client.parser.predictions for an html source
+type htmlParser struct {
+	onHTML      string
+	iterator    string
+	leftTeam    string
+	rightTeam   string
+	margin      string
+	leftMargin  string
+	rightMargin string
+	marginRegex *regexp.Regexp
+	marginSign  string
+}
+
+// Validate confirms a sources parser configuration can be used by the html provider
+func (h htmlProvider) Validate(s *Source) error {
+	_, err := h.parser(s)
+	return err
+}
+
+// Iterator returns the selector iterating fixtures
+func (h htmlProvider) Iterator(s *Source) (string, string) {
+	return "attr_t_iterator", parserOptions(h.defaults, s)["attr_t_iterator"]
+}
+
+// parser merges provider defaults with the sources parser configuration
+func (h htmlProvider) parser(s *Source) (htmlParser, error) {
+
+	var err error
+
+	options := parserOptions(h.defaults, s)
+
+	p := htmlParser{
+		onHTML:      options["attr_onhtml"],
+		iterator:    options["attr_t_iterator"],
+		leftTeam:    options["attr_t_leftteam"],
+		rightTeam:   options["attr_t_rightteam"],
+		margin:      options["attr_t_margin"],
+		leftMargin:  options["attr_t_leftmargin"],
+		rightMargin: options["attr_t_rightmargin"],
+		marginSign:  options["margin_sign"],
+	}
+
+	if p.marginSign == "" {
+		p.marginSign = marginSigned
+	}
+
+	switch {
+	case p.onHTML == "" || p.iterator == "":
+		return p, errors.New("html source requires attr_onhtml and attr_t_iterator")
+	case p.leftTeam == "" || p.rightTeam == "":
+		return p, errors.New("html source requires attr_t_leftteam and attr_t_rightteam")
+	case p.marginSign == marginSigned && p.margin == "":
+		return p, errors.New("html source with signed margins requires attr_t_margin")
+	case p.marginSign == marginSplit && (p.leftMargin == "" || p.rightMargin == ""):
+		return p, errors.New("html source with split margins requires attr_t_leftmargin and attr_t_rightmargin")
+	case p.marginSign != marginSigned && p.marginSign != marginSplit:
+		return p, fmt.Errorf("html source has an unknown margin_sign: %s", p.marginSign)
+	}
+
+	if options["margin_regex"] != "" {
+		if p.marginRegex, err = regexp.Compile(options["margin_regex"]); err != nil {
+			return p, fmt.Errorf("html source has an invalid margin_regex: %w", err)
+		}
+	}
+
+	return p, nil
+
+}
+
+// Predictions retrieves predicted margins for an html source
+func (h htmlProvider) Predictions(ctx context.Context, s *Source) error {
+
+	var err error
+
+	p, err := h.parser(s)
+	if err != nil {
+		return err
+	}
+
+	roundID := s.sourceRound()
+
+	// Client error has occurred attempting .Visit
+	s.Client.collector.OnError(func(r *colly.Response, resError error) {
+		err = resError
+		return
+	})
+	// debug
+	s.Client.collector.OnRequest(func(r *colly.Request) {
+		helpers.Logger.Debugf("Prediction retrieval from %s", r.URL.String())
+	})
+
+	scrape := p.collect(s.Client.collector, roundID, s.team)
+
+	s.Client.withContext(ctx)
+	s.Client.collector.Visit(formatRound(s.Client.config.urls["predictions"], roundID))
+
+	s.matches = scrape.counts
+	helpers.Logger.Debugf("Selector matches for %s: %v", s.Name, scrape.counts)
+
+	if err != nil {
+		return err
+	}
+	if err = scrape.check(); err != nil {
+		return err
+	}
+
+	s.Round.Predictions = append(s.Round.Predictions, scrape.predictions...)
+
+	return nil
+
+}
+
+// htmlScrape accumulates predictions and per-selector match counts while scraping
+type htmlScrape struct {
+	parser      htmlParser
+	roundID     int
+	predictions []domain.Prediction
+	counts      map[string]int // Matches by parser key, i.e. attr_t_iterator
+	err         error          // Last fixture error
+}
+
+// collect registers the parser against collector, every page visited afterwards
+// is scraped into the returned htmlScrape
+func (p htmlParser) collect(collector *colly.Collector, roundID int, resolve resolver) *htmlScrape {
+
+	scrape := &htmlScrape{parser: p, roundID: roundID, counts: make(map[string]int)}
+
+	collector.OnHTML(formatRound(p.onHTML, roundID), func(e *colly.HTMLElement) {
+
+		scrape.counts["attr_onhtml"]++
+
+		e.ForEach(p.iterator, func(_ int, el *colly.HTMLElement) {
+
+			scrape.counts["attr_t_iterator"]++
+
+			prediction, fixtureErr := p.fixture(el, resolve, scrape.counts)
+			if fixtureErr != nil {
+				scrape.err = fixtureErr
+				return
+			}
+
+			scrape.predictions = append(scrape.predictions, prediction)
+		})
+	})
+
+	return scrape
+
+}
+
+// check applies parser health checks once scraping has completed, the first
+// selector matching nothing is reported as drift ahead of any fixture error
+func (h *htmlScrape) check() error {
+
+	if h.counts["attr_onhtml"] == 0 {
+		return h.parser.drift("attr_onhtml", formatRound(h.parser.onHTML, h.roundID), domain.CheckMatch, nil)
+	}
+	if h.counts["attr_t_iterator"] == 0 {
+		return h.parser.drift("attr_t_iterator", h.parser.iterator, domain.CheckMatch, nil)
+	}
+
+	return h.err
+
+}
+
+// drift describes a failed health check against one of the parsers selectors,
+// the page (source name) is set once the provider returns
+func (p htmlParser) drift(key string, selector string, check string, err error) error {
+	return &domain.ParserDriftError{Key: key, Selector: selector, Check: check, Err: err}
+}
+
+// team extracts and resolves a team name using the selector held under key,
+// counting non-empty matches
+func (p htmlParser) team(el *colly.HTMLElement, key string, selector string, resolve resolver, counts map[string]int) (string, error) {
+
+	name := strings.TrimSpace(el.ChildText(selector))
+	if name == "" {
+		return "", p.drift(key, selector, domain.CheckTeamName, nil)
+	}
+	counts[key]++
+
+	// Resolve scraped team names to canonical team IDs
+	team, err := resolve(name)
+	if err != nil {
+		return "", p.drift(key, selector, domain.CheckKnownTeam, err)
+	}
+
+	return team, nil
+
+}
+
+// fixture determines the predicted winner and (positive) margin for a fixture
+// based on the configured sign convention
+func (p htmlParser) fixture(el *colly.HTMLElement, resolve resolver, counts map[string]int) (domain.Prediction, error) {
+
+	leftTeam, err := p.team(el, "attr_t_leftteam", p.leftTeam, resolve, counts)
+	if err != nil {
+		return domain.Prediction{}, err
+	}
+	rightTeam, err := p.team(el, "attr_t_rightteam", p.rightTeam, resolve, counts)
+	if err != nil {
+		return domain.Prediction{}, err
+	}
+	fixture := domain.Fixture{LeftTeam: leftTeam, RightTeam: rightTeam}
+
+	if p.marginSign == marginSplit {
+
+		// The populated margin cell identifies the winning team
+		if leftMargin, ok, err := p.parseMargin(el.ChildText(p.leftMargin)); err != nil {
+			return domain.Prediction{}, p.drift("attr_t_leftmargin", p.leftMargin, domain.CheckMargin, fmt.Errorf("fixture %s: %w", fixture, err))
+		} else if ok {
+			counts["attr_t_leftmargin"]++
+			return domain.NewPrediction(fixture, abs(leftMargin)), nil
+		}
+		if rightMargin, ok, err := p.parseMargin(el.ChildText(p.rightMargin)); err != nil {
+			return domain.Prediction{}, p.drift("attr_t_rightmargin", p.rightMargin, domain.CheckMargin, fmt.Errorf("fixture %s: %w", fixture, err))
+		} else if ok {
+			counts["attr_t_rightmargin"]++
+			return domain.NewPrediction(fixture, -abs(rightMargin)), nil
+		}
+
+		return domain.Prediction{}, p.drift("attr_t_leftmargin", p.leftMargin, domain.CheckMargin,
+			fmt.Errorf("fixture %s: no margin found in either margin cell", fixture))
+
+	}
+
+	margin, ok, err := p.parseMargin(el.ChildText(p.margin))
+	if err != nil {
+		return domain.Prediction{}, p.drift("attr_t_margin", p.margin, domain.CheckMargin, fmt.Errorf("fixture %s: %w", fixture, err))
+	}
+	if !ok {
+		return domain.Prediction{}, p.drift("attr_t_margin", p.margin, domain.CheckMargin, fmt.Errorf("fixture %s: no margin found in margin cell", fixture))
+	}
+	counts["attr_t_margin"]++
+
+	return domain.NewPrediction(fixture, margin), nil
+
+}
+
+// parseMargin extracts a margin from a margin cell, applying margin_regex where set
+func (p htmlParser) parseMargin(text string) (int, bool, error) {
+
+	if p.marginRegex != nil {
+		text = p.marginRegex.FindString(text)
+	}
+
+	return parseMargin(text)
+
+}
@@ -0,0 +1,245 @@
+package sources
+
+import (
+	"brubot/internal/domain"
+	"brubot/internal/helpers"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+)
+
+func init() {
+	Register("json", jsonProvider{})
+}
+
+// jsonProvider is a generic provider for sources publishing predictions as JSON,
+// fields are mapped onto fixtures using path expressions (see helpers.JSONPath)
+// set within client.parser.predictions:
+//
+//	path_iterator:  path to the array of fixtures within the response
+//	path_leftteam:  path to the left team name, relative to each fixture
+//	path_rightteam: path to the right team name, relative to each fixture
+//	path_margin:    path to the margin, relative to each fixture
+//	path_winner:    optional path to the winning team name, when set path_margin is
+//	                treated as the winners margin, otherwise as a signed margin where
+//	                a negative margin means the right team wins
+//
+// The predictions url (client.urls.predictions) may contain %d for the (source) round ID.
+type jsonProvider struct {
+	defaults map[string]string
+}
+
+// jsonParser holds the parsed client.parser.predictions for a JSON source
+type jsonParser struct {
+	iterator  string
+	leftTeam  string
+	rightTeam string
+	margin    string
+	winner    string
+}
+
+// Validate confirms a sources parser configuration can be used by the json provider
+func (j jsonProvider) Validate(s *Source) error {
+	_, err := j.parser(s)
+	return err
+}
+
+// Iterator returns the path iterating fixtures
+func (j jsonProvider) Iterator(s *Source) (string, string) {
+	return "path_iterator", parserOptions(j.defaults, s)["path_iterator"]
+}
+
+// parser merges provider defaults with the sources parser configuration
+func (j jsonProvider) parser(s *Source) (jsonParser, error) {
+	return newJSONParser(parserOptions(j.defaults, s))
+}
+
+// newJSONParser builds a jsonParser from parser options, shared with
+// providers that decode predictions from other structured formats
+func newJSONParser(options map[string]string) (jsonParser, error) {
+
+	p := jsonParser{
+		iterator:  options["path_iterator"],
+		leftTeam:  options["path_leftteam"],
+		rightTeam: options["path_rightteam"],
+		margin:    options["path_margin"],
+		winner:    options["path_winner"],
+	}
+
+	if p.leftTeam == "" || p.rightTeam == "" || p.margin == "" {
+		return p, errors.New("json source requires path_leftteam, path_rightteam and path_margin")
+	}
+
+	return p, nil
+
+}
+
+// Predictions retrieves predicted margins for a JSON source
+func (j jsonProvider) Predictions(ctx context.Context, s *Source) error {
+
+	var err error
+
+	p, err := j.parser(s)
+	if err != nil {
+		return err
+	}
+
+	// Client error has occurred attempting .Visit
+	s.Client.collector.OnError(func(r *colly.Response, resError error) {
+		err = resError
+		return
+	})
+	// debug
+	s.Client.collector.OnRequest(func(r *colly.Request) {
+		helpers.Logger.Debugf("Prediction retrieval from %s", r.URL.String())
+	})
+
+	s.Client.collector.OnResponse(func(r *colly.Response) {
+
+		var body interface{}
+
+		if jsonErr := json.Unmarshal(r.Body, &body); jsonErr != nil {
+			err = fmt.Errorf("failed decoding JSON response from %s: %w", r.Request.URL, jsonErr)
+			return
+		}
+
+		s.matches = p.matches(body)
+		fixtures, parseErr := p.fixtures(body, s.team)
+		if parseErr != nil {
+			err = parseErr
+		}
+		s.Round.Predictions = append(s.Round.Predictions, fixtures...)
+
+	})
+
+	s.Client.withContext(ctx)
+	s.Client.collector.Visit(formatRound(s.Client.config.urls["predictions"], s.sourceRound()))
+
+	return err
+
+}
+
+// fixtures maps each element of the path_iterator array within body onto a fixture,
+// elements that cannot be mapped are skipped with the last error returned
+func (p jsonParser) fixtures(body interface{}, resolve resolver) ([]domain.Prediction, error) {
+
+	var err error
+	var fixtures []domain.Prediction
+
+	items, pathErr := helpers.JSONPath(body, p.iterator)
+	if pathErr != nil {
+		return nil, &domain.ParserDriftError{Key: "path_iterator", Selector: p.iterator, Check: domain.CheckMatch, Err: pathErr}
+	}
+
+	elements, ok := items.([]interface{})
+	if !ok {
+		return nil, &domain.ParserDriftError{Key: "path_iterator", Selector: p.iterator, Check: domain.CheckMatch,
+			Detail: "does not resolve to an array"}
+	}
+	if len(elements) == 0 {
+		return nil, &domain.ParserDriftError{Key: "path_iterator", Selector: p.iterator, Check: domain.CheckMatch}
+	}
+
+	for idx := range elements {
+
+		prediction, fixtureErr := p.fixture(elements[idx], resolve)
+		if fixtureErr != nil {
+			err = fmt.Errorf("fixture at index %d: %w", idx, fixtureErr)
+			continue
+		}
+		fixtures = append(fixtures, prediction)
+
+	}
+
+	return fixtures, err
+
+}
+
+// matches counts the elements path_iterator resolves to within body and, by parser key,
+// the elements each field path resolves within, reported in place of selector matches
+func (p jsonParser) matches(body interface{}) map[string]int {
+
+	paths := map[string]string{
+		"path_leftteam":  p.leftTeam,
+		"path_rightteam": p.rightTeam,
+		"path_margin":    p.margin,
+	}
+	if p.winner != "" {
+		paths["path_winner"] = p.winner
+	}
+
+	return pathMatches(body, p.iterator, paths)
+
+}
+
+// fixture maps a single decoded element onto a fixture, resolving team names with resolve
+func (p jsonParser) fixture(element interface{}, resolve resolver) (domain.Prediction, error) {
+
+	leftTeam, err := p.team(element, "path_leftteam", p.leftTeam, resolve)
+	if err != nil {
+		return domain.Prediction{}, err
+	}
+	rightTeam, err := p.team(element, "path_rightteam", p.rightTeam, resolve)
+	if err != nil {
+		return domain.Prediction{}, err
+	}
+
+	marginText, err := helpers.JSONPathString(element, p.margin)
+	if err != nil {
+		return domain.Prediction{}, &domain.ParserDriftError{Key: "path_margin", Selector: p.margin, Check: domain.CheckMatch, Err: err}
+	}
+	margin, ok, err := parseMargin(marginText)
+	if err != nil {
+		return domain.Prediction{}, &domain.ParserDriftError{Key: "path_margin", Selector: p.margin, Check: domain.CheckMargin, Err: err}
+	}
+	if !ok {
+		return domain.Prediction{}, &domain.ParserDriftError{Key: "path_margin", Selector: p.margin, Check: domain.CheckMargin,
+			Err: errors.New("no margin found")}
+	}
+
+	if p.winner == "" {
+		return domain.NewPrediction(domain.Fixture{LeftTeam: leftTeam, RightTeam: rightTeam}, margin), nil
+	}
+
+	winner, err := p.team(element, "path_winner", p.winner, resolve)
+	if err != nil {
+		return domain.Prediction{}, err
+	}
+
+	if winner != leftTeam && winner != rightTeam {
+		return domain.Prediction{}, fmt.Errorf("winner %s is neither %s or %s", winner, leftTeam, rightTeam)
+	}
+
+	if winner == rightTeam {
+		return domain.NewPrediction(domain.Fixture{LeftTeam: leftTeam, RightTeam: rightTeam}, -abs(margin)), nil
+	}
+
+	return domain.NewPrediction(domain.Fixture{LeftTeam: leftTeam, RightTeam: rightTeam}, abs(margin)), nil
+
+}
+
+// team extracts and resolves a team name at path (held under key) within element,
+// failures are reported as parser drift
+func (p jsonParser) team(element interface{}, key string, path string, resolve resolver) (string, error) {
+
+	name, err := helpers.JSONPathString(element, path)
+	if err != nil {
+		return "", &domain.ParserDriftError{Key: key, Selector: path, Check: domain.CheckMatch, Err: err}
+	}
+	if strings.TrimSpace(name) == "" {
+		return "", &domain.ParserDriftError{Key: key, Selector: path, Check: domain.CheckTeamName}
+	}
+
+	// Resolve team names to canonical team IDs
+	team, err := resolve(name)
+	if err != nil {
+		return "", &domain.ParserDriftError{Key: key, Selector: path, Check: domain.CheckKnownTeam, Err: err}
+	}
+
+	return team, nil
+
+}
@@ -1,63 +1,265 @@
 package sources
 
 import (
+	"brubot/internal/domain"
 	"brubot/internal/helpers"
+	"context"
+	"database/sql"
 	"fmt"
+	"math"
+
+	"github.com/lib/pq"
 )
 
-// Margins figures out the best margins in town from retrievd predictions and previous results
-func (s *Sources) Margins(roundID int) (map[string]int, error) {
+// Margin is the aggregated prediction for a single fixture across all sources, the fixtures
+// teams are oriented as first seen across sources and the predicted winner (or domain.Draw
+// when the aggregated margin rounds to 0) is derived from the aggregated signed margin
+type Margin struct {
+	domain.Prediction
+	SignedMargin  float64        // Aggregated signed margin, left minus right
+	TotalWeight   float64        // Sum of weights of the contributing sources
+	Strategy      string         // Name of the aggregation strategy used
+	Contributions []Contribution // Each sources prediction for the fixture
+}
+
+// Contribution is a single sources prediction for a fixture
+type Contribution struct {
+	Source       string  // Source name
+	Weight       float64 // Source weight, unweighted sources count as 1
+	SignedMargin int     // Sources margin, left minus right
+}
+
+// Margins figures out the best margins in town from retrieved predictions, aggregating each fixture
+// from every sources signed margin (left minus right) using the configured aggregation strategy.
+// The winner is derived from the aggregated margin, aggregated predictions are recorded alongside
+// the strategy used.
+func (s *Sources) Margins(roundID int, db *sql.DB) ([]Margin, error) {
+	return s.MarginsWith(roundID, db, s.aggregator)
+}
+
+// MarginsWith aggregates retrieved predictions as Margins does, using aggregator in place of
+// the configured aggregation strategy (i.e. for a target account with its own strategy)
+func (s *Sources) MarginsWith(roundID int, db *sql.DB, aggregator Aggregator) ([]Margin, error) {
+
+	// Fitted weights override configured weights
+	if s.weightsFrom == WeightsFromDB {
+		if err := s.loadWeights(db); err != nil {
+			return nil, err
+		}
+	}
+
+	predictions, err := s.aggregateMargins(roundID, aggregator)
+
+	if s.replaying {
+		helpers.Logger.Info("Replaying archived run, margins update skipped")
+		return predictions, err
+	}
+
+	if dbErr := updateMargins(roundID, predictions, db); dbErr != nil {
+		return predictions, dbErr
+	}
+
+	return predictions, err
+
+}
+
+// aggregateMargins groups every sources predictions by fixture, combining the contributions
+// to each fixture using aggregator (the weighted mean when nil)
+func (s *Sources) aggregateMargins(roundID int, aggregator Aggregator) ([]Margin, error) {
 
 	var err error
-	var margins map[string]int
-	margins = make(map[string]int)
 
-	// In each source find fixtures with matching *winners* (for now, assuming only one team can win one fixture per round),
-	// and calculate weighted margins where applicable
+	if aggregator == nil {
+		aggregator = weightedMean{}
+	}
+	var predictions []Margin
+
 	for idx := range s.Sources {
-		for f := range s.Sources[idx].Round.Fixtures {
-			if _, ok := margins[s.Sources[idx].Round.Fixtures[f].winner]; ok {
-				if s.Sources[idx].Weight != 0 {
-					// Add new weighted margin to predictions existing aggregate weighted margin
-					margins[s.Sources[idx].Round.Fixtures[f].winner] = margins[s.Sources[idx].Round.Fixtures[f].winner] + int(float64(s.Sources[idx].Round.Fixtures[f].margin)*s.Sources[idx].Weight)
-					helpers.Logger.Debugf("Margin updated from source: %s, winner: %s, weigthed margin now: %d",
-						s.Sources[idx].Name,
-						s.Sources[idx].Round.Fixtures[f].winner,
-						margins[s.Sources[idx].Round.Fixtures[f].winner],
-					)
-				} else {
-					// Matched margin prediction without a weighted source, implication being there are 2 sources with the same
-					// tournament that should have margins aggregated using weighted averages but dont have weights
-					helpers.Logger.Errorf("Margin matched from new source without a weight, source: %s", s.Sources[idx].Name)
+		for f := range s.Sources[idx].Round.Predictions {
+
+			prediction := s.Sources[idx].Round.Predictions[f]
+			signedMargin := prediction.Signed()
+
+			// Predictions are grouped by fixture within a tournament. Sources may list a fixtures
+			// teams in either order, orient each contribution to the first sources left and right teams
+			p := -1
+			for m := range predictions {
+				if predictions[m].Fixture.Matches(prediction.Fixture) {
+					p = m
+					if predictions[m].Fixture.Reversed(prediction.Fixture) {
+						signedMargin = -signedMargin
+					}
+					if predictions[m].Tournament == "" {
+						predictions[m].Tournament = prediction.Tournament
+					}
+					break
+				}
+			}
+			if p == -1 {
+				p = len(predictions)
+				predictions = append(predictions, Margin{Prediction: domain.Prediction{Fixture: prediction.Fixture}})
+			}
+
+			weight := s.Sources[idx].Weight
+			if weight == 0 {
+				weight = 1
+			}
+
+			predictions[p].Contributions = append(predictions[p].Contributions, Contribution{
+				Source:       s.Sources[idx].Name,
+				Weight:       weight,
+				SignedMargin: signedMargin,
+			})
+
+			helpers.Logger.Debugf("Margin contribution from source: %s, fixture: %s v %s, weight: %.2f, signed margin: %d",
+				s.Sources[idx].Name,
+				predictions[p].LeftTeam,
+				predictions[p].RightTeam,
+				weight,
+				signedMargin,
+			)
+
+		}
+	}
+
+	for p := range predictions {
+
+		// A prediction matched across sources without a weight, implication being there are
+		// 2 sources with the same tournament that should have margins aggregated using weighted averages
+		if len(predictions[p].Contributions) > 1 {
+			for _, c := range predictions[p].Contributions {
+				if s.source(c.Source) != nil && s.source(c.Source).Weight == 0 {
+					helpers.Logger.Errorf("Margin matched from source without a weight, source: %s", c.Source)
 					if err == nil {
-						err = fmt.Errorf("Margin matched from new source without a weight, source: %s", s.Sources[idx].Name)
+						err = fmt.Errorf("Margin matched from source without a weight, source: %s", c.Source)
 					} else {
-						err = fmt.Errorf("%w, Margin matched from new source without a weight, source: %s", err, s.Sources[idx].Name)
+						err = fmt.Errorf("%w, Margin matched from source without a weight, source: %s", err, c.Source)
 					}
 				}
-			} else {
-				// Add new margin for sources with weight specified (calculating weighted average)
-				if s.Sources[idx].Weight != 0 {
-					margins[s.Sources[idx].Round.Fixtures[f].winner] = int(float64(s.Sources[idx].Round.Fixtures[f].margin) * s.Sources[idx].Weight)
-					helpers.Logger.Debugf("Margin with weighted prediction added from source: %s, winner: %s, weighted margin: %d",
-						s.Sources[idx].Name,
-						s.Sources[idx].Round.Fixtures[f].winner,
-						margins[s.Sources[idx].Round.Fixtures[f].winner],
-					)
-				} else {
-					// Add new margin for sources without weight specified (source margin is our margin)
-					margins[s.Sources[idx].Round.Fixtures[f].winner] = s.Sources[idx].Round.Fixtures[f].margin
-					helpers.Logger.Debugf("Margin without weighted prediction added from source: %s, winner: %s, margin: %d",
-						s.Sources[idx].Name,
-						s.Sources[idx].Round.Fixtures[f].winner,
-						margins[s.Sources[idx].Round.Fixtures[f].winner],
-					)
-				}
 			}
 		}
 
+		predictions[p].aggregate(aggregator)
+
+		helpers.Logger.Debugf("Margin aggregated (%s) for round: %d, tournament: %s, fixture: %s v %s, sources: %d, total weight: %.2f, "+
+			"signed margin: %.2f, winner: %s, margin: %d",
+			aggregator.Name(),
+			roundID,
+			predictions[p].Tournament,
+			predictions[p].LeftTeam,
+			predictions[p].RightTeam,
+			len(predictions[p].Contributions),
+			predictions[p].TotalWeight,
+			predictions[p].SignedMargin,
+			predictions[p].Winner,
+			predictions[p].Margin,
+		)
+
+	}
+
+	return predictions, err
+
+}
+
+// aggregate sets SignedMargin by combining all contributions with aggregator,
+// deriving Winner and Margin from the rounded result
+func (p *Margin) aggregate(aggregator Aggregator) {
+
+	p.Strategy = aggregator.Name()
+	p.TotalWeight = 0
+	for _, c := range p.Contributions {
+		p.TotalWeight += c.Weight
 	}
 
-	return margins, err
+	if len(p.Contributions) == 0 {
+		return
+	}
+
+	p.SignedMargin = aggregator.Aggregate(p.Contributions)
+	p.Prediction = domain.NewPrediction(p.Fixture, int(math.Round(p.SignedMargin)))
+
+}
+
+// ToPredictions strips aggregation detail from margins, leaving a prediction per fixture
+func ToPredictions(margins []Margin) []domain.Prediction {
+
+	predictions := make([]domain.Prediction, len(margins))
+	for idx := range margins {
+		predictions[idx] = margins[idx].Prediction
+	}
+
+	return predictions
+
+}
+
+// updateMargins records aggregated predictions for a round along with the aggregation strategy used,
+// allowing strategies to be compared against results across runs
+func updateMargins(roundID int, predictions []Margin, db *sql.DB) error {
+
+	// Temporary ID for duplicate margin checking
+	var tmpID int
+	// Create an empty context for update transaction
+	sqlCtx := context.Background()
+	// Start sql transaction
+	sqlTxn, err := db.BeginTx(sqlCtx, nil)
+	if err != nil {
+		return err
+	}
+	// prepare sql statement with COPY FROM
+	sqlStmt, err := sqlTxn.Prepare(pq.CopyIn("margins", "round_id", "strategy", "tournament", "leftteam", "rightteam", "winner", "margin", "sources"))
+	if err != nil {
+		return err
+	}
+
+	helpers.Logger.Debug("Margin update is emminent, hold tight...")
+
+	for p := range predictions {
+
+		// Same "Ugly Check" as source prediction update
+		sqlMrgExists := db.QueryRowContext(sqlCtx,
+			"SELECT id FROM margins WHERE round_id=$1 "+
+				"AND strategy=$2 AND tournament=$3 AND leftteam=$4 AND rightteam=$5 "+
+				"AND winner=$6 AND margin=$7",
+			roundID,
+			predictions[p].Strategy,
+			predictions[p].Tournament,
+			predictions[p].LeftTeam,
+			predictions[p].RightTeam,
+			predictions[p].Winner,
+			predictions[p].Margin).Scan(&tmpID)
+		switch {
+		case sqlMrgExists == sql.ErrNoRows:
+			_, err = sqlStmt.Exec(
+				roundID,
+				predictions[p].Strategy,
+				predictions[p].Tournament,
+				predictions[p].LeftTeam,
+				predictions[p].RightTeam,
+				predictions[p].Winner,
+				predictions[p].Margin,
+				len(predictions[p].Contributions),
+			)
+			if err != nil {
+				return err
+			}
+		case sqlMrgExists != nil:
+			// Error occurred during query
+			return sqlMrgExists
+		default:
+			helpers.Logger.Debugf("Margin update omitted as record already exists with ID: %d", tmpID)
+		}
+	}
+	err = sqlStmt.Close()
+	if err != nil {
+		return err
+	}
+	err = sqlTxn.Commit()
+	if err != nil {
+		return err
+	}
+
+	helpers.Logger.Debug("Margin update completed sans incidents")
+
+	return nil
 
 }
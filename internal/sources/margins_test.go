@@ -0,0 +1,140 @@
+package sources
+
+import (
+	"brubot/internal/domain"
+	"math"
+	"testing"
+)
+
+func TestAggregateWeightedMean(t *testing.T) {
+
+	c := func(margin int, weight float64) Contribution {
+		return Contribution{SignedMargin: margin, Weight: weight}
+	}
+
+	tests := []struct {
+		name          string
+		contributions []Contribution
+		wantSigned    float64
+		wantWinner    string
+		wantMargin    int
+	}{
+		{"single source", []Contribution{c(10, 0.3)}, 10, "blues", 10},
+		{"single source right", []Contribution{c(-4, 1)}, -4, "chiefs", 4},
+		{"weighted", []Contribution{c(10, 1), c(-2, 3)}, 1, "blues", 1},
+		{"normalised by weight", []Contribution{c(9, 0.2), c(3, 0.1)}, 7, "blues", 7},
+		{"tie is a draw", []Contribution{c(6, 1), c(-6, 1)}, 0, "draw", 0},
+		{"rounds to a draw", []Contribution{c(1, 1), c(-1, 1), c(0, 2)}, 0, "draw", 0},
+		{"rounds half away from zero", []Contribution{c(3, 1), c(-2, 1)}, 0.5, "blues", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			p := Margin{Prediction: domain.Prediction{Fixture: domain.Fixture{LeftTeam: "blues", RightTeam: "chiefs"}}, Contributions: tt.contributions}
+			p.aggregate(weightedMean{})
+
+			if math.Abs(p.SignedMargin-tt.wantSigned) > 1e-9 || p.Winner != tt.wantWinner || p.Margin != tt.wantMargin {
+				t.Errorf("aggregate() = %.2f (%s by %d), want %.2f (%s by %d)",
+					p.SignedMargin, p.Winner, p.Margin, tt.wantSigned, tt.wantWinner, tt.wantMargin)
+			}
+
+		})
+	}
+
+}
+
+func TestMarginsOrientation(t *testing.T) {
+
+	fixture := func(left string, right string) domain.Fixture {
+		return domain.Fixture{LeftTeam: left, RightTeam: right, Tournament: "super rugby"}
+	}
+
+	tests := []struct {
+		name        string
+		predictions [][]domain.Prediction // Per source, each weighted 1
+		want        []domain.Prediction
+	}{
+		{
+			name: "single source",
+			predictions: [][]domain.Prediction{
+				{domain.NewPrediction(fixture("blues", "chiefs"), 10)},
+			},
+			want: []domain.Prediction{domain.NewPrediction(fixture("blues", "chiefs"), 10)},
+		},
+		{
+			name: "reversed fixture disagreeing",
+			predictions: [][]domain.Prediction{
+				{domain.NewPrediction(fixture("blues", "chiefs"), 10)},
+				{domain.NewPrediction(fixture("chiefs", "blues"), 4)}, // chiefs by 4
+			},
+			want: []domain.Prediction{domain.NewPrediction(fixture("blues", "chiefs"), 3)},
+		},
+		{
+			name: "reversed fixture agreeing",
+			predictions: [][]domain.Prediction{
+				{domain.NewPrediction(fixture("blues", "chiefs"), 10)},
+				{domain.NewPrediction(fixture("chiefs", "blues"), -6)}, // blues by 6
+			},
+			want: []domain.Prediction{domain.NewPrediction(fixture("blues", "chiefs"), 8)},
+		},
+		{
+			name: "reversed fixture tied",
+			predictions: [][]domain.Prediction{
+				{domain.NewPrediction(fixture("blues", "chiefs"), 4)},
+				{domain.NewPrediction(fixture("chiefs", "blues"), 4)},
+			},
+			want: []domain.Prediction{{Fixture: fixture("blues", "chiefs"), Winner: domain.Draw}},
+		},
+		{
+			name: "oriented to the first source",
+			predictions: [][]domain.Prediction{
+				{domain.NewPrediction(fixture("chiefs", "blues"), 2)},
+				{domain.NewPrediction(fixture("blues", "chiefs"), 8)},
+				{domain.NewPrediction(fixture("blues", "chiefs"), 3)},
+			},
+			want: []domain.Prediction{domain.NewPrediction(fixture("chiefs", "blues"), -3)},
+		},
+		{
+			name: "other tournaments are separate fixtures",
+			predictions: [][]domain.Prediction{
+				{domain.NewPrediction(fixture("blues", "chiefs"), 10)},
+				{domain.NewPrediction(domain.Fixture{LeftTeam: "chiefs", RightTeam: "blues", Tournament: "npc"}, 4)},
+			},
+			want: []domain.Prediction{
+				domain.NewPrediction(fixture("blues", "chiefs"), 10),
+				domain.NewPrediction(domain.Fixture{LeftTeam: "chiefs", RightTeam: "blues", Tournament: "npc"}, 4),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			s := Sources{}
+			for idx, predictions := range tt.predictions {
+				s.Sources = append(s.Sources, Source{
+					Name:   string(rune('a' + idx)),
+					Weight: 1,
+					Round:  domain.Round{Predictions: predictions},
+				})
+			}
+
+			margins, err := s.aggregateMargins(1, weightedMean{})
+			if err != nil {
+				t.Fatalf("aggregateMargins: %v", err)
+			}
+			got := ToPredictions(margins)
+			if len(got) != len(tt.want) {
+				t.Fatalf("aggregateMargins() = %v, want %v", got, tt.want)
+			}
+			for idx := range got {
+				if got[idx] != tt.want[idx] {
+					t.Errorf("aggregateMargins()[%d] = %+v, want %+v", idx, got[idx], tt.want[idx])
+				}
+			}
+
+		})
+	}
+
+}
@@ -0,0 +1,329 @@
+package sources
+
+import (
+	"brubot/internal/domain"
+	"brubot/internal/helpers"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+)
+
+func init() {
+	Register("odds", oddsProvider{})
+}
+
+// Odds to margin conversion methods
+const (
+	oddsAuto     = "auto"     // use the handicap line where available, otherwise logistic
+	oddsLine     = "line"     // the handicap line is the predicted margin
+	oddsLogistic = "logistic" // margin is a logistic mapping of implied win probability
+)
+
+// oddsProvider converts bookmaker head-to-head decimal odds and/or handicap lines into
+// a predicted winner and margin, allowing betting markets to be weighted alongside tipsters.
+// Markets are scraped from html or JSON (odds_format) using client.parser.predictions:
+//
+//	odds_format:  html (default) or json
+//	odds_method:  auto (default), line or logistic
+//	odds_scale:   points per unit of log-odds for the logistic method, defaults to 10
+//
+//	html:  attr_onhtml, attr_t_iterator, attr_t_leftteam, attr_t_rightteam
+//	       attr_t_leftodds, attr_t_rightodds and/or attr_t_line
+//	json:  path_iterator, path_leftteam, path_rightteam
+//	       path_leftodds, path_rightodds and/or path_line
+//
+// The line is the left teams handicap, i.e. -7.5 means the left team is expected to win by 7.5.
+// The logistic method removes the bookmakers overround from head-to-head odds to get the left teams
+// implied probability p, the predicted (signed) margin is then odds_scale * ln(p / (1 - p)).
+type oddsProvider struct {
+	defaults map[string]string
+}
+
+// oddsParser holds the parsed client.parser.predictions for an odds source
+type oddsParser struct {
+	format  string
+	method  string
+	scale   float64
+	options map[string]string
+}
+
+// Validate confirms a sources parser configuration can be used by the odds provider
+func (o oddsProvider) Validate(s *Source) error {
+	_, err := o.parser(s)
+	return err
+}
+
+// Iterator returns the selector or path iterating markets, based on odds_format
+func (o oddsProvider) Iterator(s *Source) (string, string) {
+
+	options := parserOptions(o.defaults, s)
+	if options["odds_format"] == "json" {
+		return "path_iterator", options["path_iterator"]
+	}
+
+	return "attr_t_iterator", options["attr_t_iterator"]
+
+}
+
+// parser merges provider defaults with the sources parser configuration
+func (o oddsProvider) parser(s *Source) (oddsParser, error) {
+
+	var err error
+
+	p := oddsParser{
+		format:  "html",
+		method:  oddsAuto,
+		scale:   10,
+		options: parserOptions(o.defaults, s),
+	}
+
+	if p.options["odds_format"] != "" {
+		p.format = p.options["odds_format"]
+	}
+	if p.options["odds_method"] != "" {
+		p.method = p.options["odds_method"]
+	}
+	if p.options["odds_scale"] != "" {
+		if p.scale, err = strconv.ParseFloat(p.options["odds_scale"], 64); err != nil || p.scale <= 0 {
+			return p, fmt.Errorf("odds source has an invalid odds_scale: %q", p.options["odds_scale"])
+		}
+	}
+
+	switch p.format {
+	case "html":
+		if p.options["attr_onhtml"] == "" || p.options["attr_t_iterator"] == "" {
+			return p, errors.New("html odds source requires attr_onhtml and attr_t_iterator")
+		}
+	case "json":
+	default:
+		return p, fmt.Errorf("odds source has an unknown odds_format: %s", p.format)
+	}
+
+	hasOdds := p.key("leftodds") != "" && p.key("rightodds") != ""
+	hasLine := p.key("line") != ""
+
+	switch {
+	case p.key("leftteam") == "" || p.key("rightteam") == "":
+		return p, errors.New("odds source requires a left and right team selector or path")
+	case p.method == oddsLine && !hasLine:
+		return p, errors.New("odds source using the line method requires a line selector or path")
+	case p.method == oddsLogistic && !hasOdds:
+		return p, errors.New("odds source using the logistic method requires left and right odds selectors or paths")
+	case p.method == oddsAuto && !hasOdds && !hasLine:
+		return p, errors.New("odds source requires odds and/or line selectors or paths")
+	case p.method != oddsAuto && p.method != oddsLine && p.method != oddsLogistic:
+		return p, fmt.Errorf("odds source has an unknown odds_method: %s", p.method)
+	}
+
+	return p, nil
+
+}
+
+// paths returns the configured selector or path by parser key for every field set
+func (p oddsParser) paths() map[string]string {
+
+	prefix := "attr_t_"
+	if p.format == "json" {
+		prefix = "path_"
+	}
+
+	paths := make(map[string]string)
+	for _, field := range []string{"leftteam", "rightteam", "leftodds", "rightodds", "line"} {
+		if p.key(field) != "" {
+			paths[prefix+field] = p.key(field)
+		}
+	}
+
+	return paths
+
+}
+
+// key returns the configured selector or path for a field based on odds_format
+func (p oddsParser) key(field string) string {
+
+	if p.format == "json" {
+		return p.options["path_"+field]
+	}
+
+	return p.options["attr_t_"+field]
+
+}
+
+// Predictions retrieves markets for an odds source and converts them to predicted margins
+func (o oddsProvider) Predictions(ctx context.Context, s *Source) error {
+
+	var err error
+
+	p, err := o.parser(s)
+	if err != nil {
+		return err
+	}
+
+	roundID := s.sourceRound()
+
+	// Client error has occurred attempting .Visit
+	s.Client.collector.OnError(func(r *colly.Response, resError error) {
+		err = resError
+		return
+	})
+	// debug
+	s.Client.collector.OnRequest(func(r *colly.Request) {
+		helpers.Logger.Debugf("Odds retrieval from %s", r.URL.String())
+	})
+
+	// appendFixture converts a single market, field returns the raw
+	// value for a field regardless of odds_format
+	appendFixture := func(field func(name string) (string, error)) {
+		prediction, fixtureErr := p.fixture(field, s.team)
+		if fixtureErr != nil {
+			err = fixtureErr
+			return
+		}
+		s.Round.Predictions = append(s.Round.Predictions, prediction)
+	}
+
+	if p.format == "json" {
+
+		s.Client.collector.OnResponse(func(r *colly.Response) {
+
+			var body interface{}
+
+			if jsonErr := json.Unmarshal(r.Body, &body); jsonErr != nil {
+				err = fmt.Errorf("failed decoding JSON response from %s: %w", r.Request.URL, jsonErr)
+				return
+			}
+
+			s.matches = pathMatches(body, p.options["path_iterator"], p.paths())
+
+			items, pathErr := helpers.JSONPath(body, p.options["path_iterator"])
+			if pathErr != nil {
+				err = pathErr
+				return
+			}
+			elements, ok := items.([]interface{})
+			if !ok {
+				err = fmt.Errorf("path_iterator %s does not resolve to an array", p.options["path_iterator"])
+				return
+			}
+
+			for idx := range elements {
+				element := elements[idx]
+				appendFixture(func(name string) (string, error) {
+					if p.key(name) == "" {
+						return "", nil
+					}
+					return helpers.JSONPathString(element, p.key(name))
+				})
+			}
+
+		})
+
+	} else {
+
+		// Selector matches by parser key, counting non-empty field matches
+		s.matches = map[string]int{"attr_onhtml": 0, "attr_t_iterator": 0}
+		for key := range p.paths() {
+			s.matches[key] = 0
+		}
+
+		s.Client.collector.OnHTML(formatRound(p.options["attr_onhtml"], roundID), func(e *colly.HTMLElement) {
+			s.matches["attr_onhtml"]++
+			e.ForEach(p.options["attr_t_iterator"], func(_ int, el *colly.HTMLElement) {
+				s.matches["attr_t_iterator"]++
+				for key, selector := range p.paths() {
+					if strings.TrimSpace(el.ChildText(selector)) != "" {
+						s.matches[key]++
+					}
+				}
+				appendFixture(func(name string) (string, error) {
+					if p.key(name) == "" {
+						return "", nil
+					}
+					return el.ChildText(p.key(name)), nil
+				})
+			})
+		})
+
+	}
+
+	s.Client.withContext(ctx)
+	s.Client.collector.Visit(formatRound(s.Client.config.urls["predictions"], roundID))
+
+	return err
+
+}
+
+// fixture converts a single market into a fixture, field returns the raw value for a field
+// and resolve maps team names to canonical team IDs
+func (p oddsParser) fixture(field func(name string) (string, error), resolve resolver) (domain.Prediction, error) {
+
+	values := make(map[string]string)
+	for _, name := range []string{"leftteam", "rightteam", "leftodds", "rightodds", "line"} {
+		val, err := field(name)
+		if err != nil {
+			return domain.Prediction{}, err
+		}
+		values[name] = strings.TrimSpace(val)
+	}
+
+	leftTeam, err := resolve(values["leftteam"])
+	if err != nil {
+		return domain.Prediction{}, err
+	}
+	rightTeam, err := resolve(values["rightteam"])
+	if err != nil {
+		return domain.Prediction{}, err
+	}
+
+	margin, err := p.margin(values["leftodds"], values["rightodds"], values["line"])
+	if err != nil {
+		return domain.Prediction{}, fmt.Errorf("market %s v %s: %w", leftTeam, rightTeam, err)
+	}
+
+	helpers.Logger.Debugf("Odds converted using %s, leftTeam: %s (%s), rightTeam: %s (%s), line: %s, signed margin: %d",
+		p.method, leftTeam, values["leftodds"], rightTeam, values["rightodds"], values["line"], margin)
+
+	return domain.NewPrediction(domain.Fixture{LeftTeam: leftTeam, RightTeam: rightTeam}, margin), nil
+
+}
+
+// margin converts odds and/or a line into a signed margin from the left teams perspective
+func (p oddsParser) margin(leftOdds string, rightOdds string, line string) (int, error) {
+
+	method := p.method
+	if method == oddsAuto {
+		method = oddsLogistic
+		if line != "" {
+			method = oddsLine
+		}
+	}
+
+	if method == oddsLine {
+		handicap, err := strconv.ParseFloat(line, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid line: %q", line)
+		}
+		return int(math.Round(-handicap)), nil
+	}
+
+	left, err := strconv.ParseFloat(leftOdds, 64)
+	if err != nil || left <= 1 {
+		return 0, fmt.Errorf("invalid decimal odds: %q", leftOdds)
+	}
+	right, err := strconv.ParseFloat(rightOdds, 64)
+	if err != nil || right <= 1 {
+		return 0, fmt.Errorf("invalid decimal odds: %q", rightOdds)
+	}
+
+	// Normalise implied probabilities to remove the bookmakers overround
+	probability := (1 / left) / ((1 / left) + (1 / right))
+
+	return int(math.Round(p.scale * math.Log(probability/(1-probability)))), nil
+
+}
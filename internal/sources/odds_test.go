@@ -0,0 +1,57 @@
+package sources
+
+import "testing"
+
+func TestOddsMargin(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		method    string
+		scale     float64
+		leftOdds  string
+		rightOdds string
+		line      string
+		want      int
+		wantErr   bool
+	}{
+		// The line is the left teams handicap, negated and rounded half away from zero
+		{"line favourite", oddsLine, 10, "", "", "-7.5", 8, false},
+		{"line underdog", oddsLine, 10, "", "", "3.5", -4, false},
+		{"line pick'em", oddsLine, 10, "", "", "0", 0, false},
+		{"line rounds to draw", oddsLine, 10, "", "", "-0.4", 0, false},
+		{"line invalid", oddsLine, 10, "1.5", "2.5", "", 0, true},
+
+		// p = (1/1.5) / (1/1.5 + 1/2.5) = 0.625, 10 * ln(0.625 / 0.375) = 5.11
+		{"logistic favourite", oddsLogistic, 10, "1.5", "2.5", "", 5, false},
+		{"logistic underdog", oddsLogistic, 10, "2.5", "1.5", "", -5, false},
+		// Implied probabilities 0.8 + 0.25 carry a 5% overround, p = 0.8 / 1.05 = 0.762,
+		// 10 * ln(0.762 / 0.238) = 11.63
+		{"logistic overround removed", oddsLogistic, 10, "1.25", "4.0", "", 12, false},
+		{"logistic scaled", oddsLogistic, 20, "1.25", "4.0", "", 23, false},
+		// Equal odds are p = 0.5 whatever the overround
+		{"logistic even", oddsLogistic, 10, "1.9", "1.9", "", 0, false},
+		{"logistic even without overround", oddsLogistic, 10, "2.0", "2.0", "", 0, false},
+		{"logistic odds of 1", oddsLogistic, 10, "1.0", "2.5", "", 0, true},
+		{"logistic odds below 1", oddsLogistic, 10, "1.5", "0.8", "", 0, true},
+		{"logistic odds invalid", oddsLogistic, 10, "evens", "2.0", "", 0, true},
+
+		// Auto prefers the line, falling back to odds
+		{"auto with line", oddsAuto, 10, "1.25", "4.0", "-2.5", 3, false},
+		{"auto without line", oddsAuto, 10, "1.25", "4.0", "", 12, false},
+		{"auto without line or valid odds", oddsAuto, 10, "1.0", "4.0", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := oddsParser{method: tt.method, scale: tt.scale}
+			got, err := p.margin(tt.leftOdds, tt.rightOdds, tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("margin(%q, %q, %q) err = %v, want error %v", tt.leftOdds, tt.rightOdds, tt.line, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("margin(%q, %q, %q) = %d, want %d", tt.leftOdds, tt.rightOdds, tt.line, got, tt.want)
+			}
+		})
+	}
+
+}
@@ -0,0 +1,97 @@
+package sources
+
+import (
+	"brubot/internal/helpers"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// parserOptions merges provider defaults with a sources client.parser.predictions,
+// configured values taking precedence
+func parserOptions(defaults map[string]string, s *Source) map[string]string {
+
+	options := make(map[string]string)
+	for key, val := range defaults {
+		options[key] = val
+	}
+	for key, val := range s.Client.parser.predictions {
+		options[key] = val
+	}
+
+	return options
+
+}
+
+// pathMatches counts the elements iterator resolves to within body and, by parser key,
+// the elements each of paths resolves to a non-null value within
+func pathMatches(body interface{}, iterator string, paths map[string]string) map[string]int {
+
+	matches := map[string]int{"path_iterator": 0}
+	for key := range paths {
+		matches[key] = 0
+	}
+
+	items, err := helpers.JSONPath(body, iterator)
+	if err != nil {
+		return matches
+	}
+	elements, ok := items.([]interface{})
+	if !ok {
+		return matches
+	}
+	matches["path_iterator"] = len(elements)
+
+	for _, element := range elements {
+		for key, path := range paths {
+			if val, err := helpers.JSONPath(element, path); err == nil && val != nil {
+				matches[key]++
+			}
+		}
+	}
+
+	return matches
+
+}
+
+// parseMargin converts margin text to an int, rounding fractional margins.
+// ok is false when text holds no margin.
+func parseMargin(text string) (int, bool, error) {
+
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, false, nil
+	}
+
+	margin, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return int(math.Round(margin)), true, nil
+
+}
+
+// abs returns the absolute value of a margin
+func abs(margin int) int {
+	return int(math.Abs(float64(margin)))
+}
+
+// formatRound populates each %d verb within a url or selector template with roundID,
+// templates without a verb are returned unchanged.
+func formatRound(template string, roundID int) string {
+
+	verbs := strings.Count(template, "%d")
+	if verbs == 0 {
+		return template
+	}
+
+	args := make([]interface{}, verbs)
+	for idx := range args {
+		args[idx] = roundID
+	}
+
+	return fmt.Sprintf(template, args...)
+
+}
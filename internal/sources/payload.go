@@ -0,0 +1,22 @@
+package sources
+
+// payload returns a payload read locally by a source (a files contents or a commands output)
+// using read, method and uri identify it within the archive (see archive.RecordPayload).
+//
+// Payloads are archived along with fetched responses when archiving is enabled, and served
+// from the archived run when replaying so files are not read and commands are not run.
+func (s *Source) payload(method string, uri string, read func() ([]byte, error)) ([]byte, error) {
+
+	if s.replay != nil {
+		return s.replay.Payload(method, uri)
+	}
+
+	body, err := read()
+	if err != nil {
+		return nil, err
+	}
+	s.archive.RecordPayload(s.Name, s.Round.ID, method, uri, body)
+
+	return body, nil
+
+}
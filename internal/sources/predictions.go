@@ -1,11 +1,14 @@
 package sources
 
 import (
+	"brubot/internal/domain"
 	"brubot/internal/helpers"
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"reflect"
+	"strings"
+	"time"
 
 	"github.com/lib/pq"
 )
@@ -13,15 +16,22 @@ import (
 // Predictions retrieves predicted margins from all sources and updates backend
 func (s *Sources) Predictions(roundID int, db *sql.DB) error {
 
-	// set roundID for each source
+	// set roundID for each source, archiving each sources responses against it
 	for idx := range s.Sources {
-		s.Sources[idx].Round.id = roundID
+		s.Sources[idx].Round.ID = roundID
+		s.Sources[idx].archive = s.Archive
+		s.Archive.Collect(s.Sources[idx].Client.collector, s.Sources[idx].Name, func() int { return roundID })
 	}
 
 	if err := s.getPredictions(); err != nil {
 		return err
 	}
 
+	if s.replaying {
+		helpers.Logger.Info("Replaying archived run, prediction update skipped")
+		return nil
+	}
+
 	if err := s.updatePredictions(db); err != nil {
 		return err
 	}
@@ -30,44 +40,227 @@ func (s *Sources) Predictions(roundID int, db *sql.DB) error {
 
 }
 
-// getPredictions iterates through all sources and uses reflection to call
-// each sources corresponding method by name, which in turn populates each source
-// with predictions per fixture
+// outcome is the result of retrieving predictions from a single source
+type outcome struct {
+	idx     int            // Index of the source within Sources
+	round   domain.Round   // Round populated by the sources provider
+	matches map[string]int // Selector matches recorded by the sources provider
+	err     error          // Error returned by the provider or a missed deadline
+}
+
+// getPredictions calls the provider registered against each source concurrently, which in turn
+// populates each source with predictions per fixture. Each source is bound by its own deadline
+// as well as the overall deadline, sources failing to return predictions are recorded against
+// the source and the partial-failure policy is applied.
+//
+// Every provider has returned by the time getPredictions does, so no provider is left
+// using a sources collector.
 func (s *Sources) getPredictions() error {
 
-	var err error
+	// Cancelled once every provider is done with, or early on failing the run
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if s.timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), time.Second*s.timeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	defer cancel()
+
+	// Providers are resolved by name during Init, so a missing provider
+	// here means Init was skipped
+	for idx := range s.Sources {
+		if s.Sources[idx].provider == nil {
+			return fmt.Errorf("Source %s has no provider, has Init been called?", s.Sources[idx].Name)
+		}
+	}
+
+	outcomes := make(chan outcome, len(s.Sources))
 
 	for idx := range s.Sources {
 
-		// Call prediction extraction method using the Source objects Name property,
-		// i.e. if the source is named foo, a method should exist of the same name for
-		// retrieving predictions
-		errv := reflect.ValueOf(&s.Sources[idx]).MethodByName(s.Sources[idx].Name).Call([]reflect.Value{})
-
-		// error checking is different here as valueof returns a reflected interface
-		if !errv[0].IsNil() {
-			// Use error wrapping to collect errors per-source but not fail outright for all
-			// prediction retrievals (fails messy)
-			if err == nil {
-				err = fmt.Errorf("Failed source: %s error: %v", s.Sources[idx].Name, errv[0].Interface().(error))
+		// Each provider works on its own copy of the source, which is only
+		// read back once the provider has returned within its deadline
+		go func(idx int, src Source) {
+
+			var srcCtx context.Context
+			var cancel context.CancelFunc
+			if src.timeout > 0 {
+				srcCtx, cancel = context.WithTimeout(ctx, time.Second*src.timeout)
 			} else {
-				err = fmt.Errorf("%w, Failed source: %s error: %v", err, s.Sources[idx].Name, errv[0].Interface().(error))
+				srcCtx, cancel = context.WithCancel(ctx)
+			}
+			defer cancel()
+
+			done := make(chan error, 1)
+			go func() {
+				done <- src.provider.Predictions(srcCtx, &src)
+			}()
+
+			select {
+			case err := <-done:
+				outcomes <- outcome{idx: idx, round: src.Round, matches: src.matches, err: err}
+			case <-srcCtx.Done():
+				// Providers give up once their context is done (requests are bound to it),
+				// the provider is waited on so the sources collector is no longer in use
+				<-done
+				outcomes <- outcome{idx: idx, matches: src.matches, err: fmt.Errorf("deadline exceeded: %w", srcCtx.Err())}
+			}
+
+		}(idx, s.Sources[idx])
+
+	}
+
+	// pending drains the outcomes of providers yet to return, used when failing early
+	pending := len(s.Sources)
+	drain := func() {
+		cancel()
+		for ; pending > 0; pending-- {
+			<-outcomes
+		}
+	}
+
+	for pending > 0 {
+
+		o := <-outcomes
+		pending--
+		s.Sources[o.idx].matches = o.matches
+
+		if o.err == nil {
+			o.err = s.Sources[o.idx].checkFixtures(o.round, s.expected)
+		}
+
+		var drift *domain.ParserDriftError
+		if errors.As(o.err, &drift) {
+			if drift.Page == "" {
+				drift.Page = s.Sources[o.idx].Name
+			}
+			// Drift fails the run outright by policy, otherwise the source is dropped
+			// and the partial-failure policy applies
+			if s.policy.drift == domain.DriftFail {
+				drain()
+				return o.err
 			}
 		}
 
-		for f := range s.Sources[idx].Round.Fixtures {
-			helpers.Logger.Debugf("Prediction has been retrieved from: %s letfTeam: %s rightTeam: %s, winner: %s, margin %d",
-				s.Sources[idx].Name,
-				s.Sources[idx].Round.Fixtures[f].leftTeam,
-				s.Sources[idx].Round.Fixtures[f].rightTeam,
-				s.Sources[idx].Round.Fixtures[f].winner,
-				s.Sources[idx].Round.Fixtures[f].margin,
+		if o.err != nil {
+			// A failed source contributes no predictions, partially retrieved
+			// predictions are discarded
+			s.Sources[o.idx].err = o.err
+			s.Sources[o.idx].Round.Predictions = nil
+			helpers.Logger.Warnf("Failed source: %s error: %v", s.Sources[o.idx].Name, o.err)
+			continue
+		}
+
+		s.Sources[o.idx].Round = o.round
+
+		for f := range s.Sources[o.idx].Round.Predictions {
+			// Predictions belong to the sources tournament, so they are only
+			// aggregated and matched to fixtures within that tournament
+			s.Sources[o.idx].Round.Predictions[f].Tournament = s.Sources[o.idx].Tournament
+			helpers.Logger.Debugf("Prediction has been retrieved from: %s tournament: %s letfTeam: %s rightTeam: %s, winner: %s, margin %d",
+				s.Sources[o.idx].Name,
+				s.Sources[o.idx].Tournament,
+				s.Sources[o.idx].Round.Predictions[f].LeftTeam,
+				s.Sources[o.idx].Round.Predictions[f].RightTeam,
+				s.Sources[o.idx].Round.Predictions[f].Winner,
+				s.Sources[o.idx].Round.Predictions[f].Margin,
 			)
 		}
 
 	}
 
-	return err
+	return s.applyPolicy()
+
+}
+
+// checkFixtures confirms predictions retrieved from a source cover every expected (target)
+// fixture within the sources tournament, a source predicting fewer fixtures has drifted.
+//
+// Only fixtures known to be within the sources tournament are counted, where either the
+// source or a fixture has no tournament the source can't be expected to cover the fixture.
+func (s *Source) checkFixtures(round domain.Round, expected []domain.Fixture) error {
+
+	var want, got int
+
+	if s.Tournament == "" {
+		return nil
+	}
+
+	for _, f := range expected {
+
+		if f.Tournament == "" || !strings.EqualFold(f.Tournament, s.Tournament) {
+			continue
+		}
+		want++
+
+		for _, prediction := range round.Predictions {
+			if f.Matches(prediction.Fixture) {
+				got++
+				break
+			}
+		}
+
+	}
+
+	if got == want {
+		return nil
+	}
+
+	drift := &domain.ParserDriftError{
+		Page:     s.Name,
+		Key:      "predictions",
+		Selector: s.Client.config.urls["predictions"],
+		Check:    domain.CheckFixtureCount,
+		Detail:   fmt.Sprintf("%d of %d target fixtures predicted", got, want),
+	}
+	if iterator, ok := s.provider.(fixtureIterator); ok {
+		drift.Key, drift.Selector = iterator.Iterator(s)
+	}
+
+	return drift
+
+}
+
+// PolicyError is returned when the sources that failed to return predictions
+// leave the run short of the configured partial-failure policy
+type PolicyError struct {
+	Reason string           // Which part of the policy has not been met
+	Failed map[string]error // Failed sources by name
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("Source policy not met: %s, failed sources: %v", e.Reason, e.Failed)
+}
+
+// applyPolicy confirms enough sources, and every required source, returned predictions
+func (s *Sources) applyPolicy() error {
+
+	failed := make(map[string]error)
+	for idx := range s.Sources {
+		if s.Sources[idx].err != nil {
+			failed[s.Sources[idx].Name] = s.Sources[idx].err
+		}
+	}
+
+	for _, name := range s.policy.required {
+		if _, ok := failed[name]; ok {
+			return &PolicyError{Reason: fmt.Sprintf("required source %s is missing", name), Failed: failed}
+		}
+	}
+
+	if succeeded := len(s.Sources) - len(failed); succeeded < s.policy.minSources {
+		return &PolicyError{
+			Reason: fmt.Sprintf("%d of %d sources returned predictions, %d required", succeeded, len(s.Sources), s.policy.minSources),
+			Failed: failed,
+		}
+	}
+
+	if len(failed) > 0 {
+		helpers.Logger.Warnf("Continuing without %d failed source(s) as policy allows", len(failed))
+	}
+
+	return nil
 
 }
 
@@ -92,7 +285,7 @@ func (s *Sources) updatePredictions(db *sql.DB) error {
 	helpers.Logger.Debug("Prediction update is emminent, hold tight...")
 
 	for idx := range s.Sources {
-		for f := range s.Sources[idx].Round.Fixtures {
+		for f := range s.Sources[idx].Round.Predictions {
 
 			// Ugly check to establish if a source already has a prediction recorded
 			// against the round id and fixture parameters
@@ -102,23 +295,23 @@ func (s *Sources) updatePredictions(db *sql.DB) error {
 				"SELECT id FROM predictions WHERE round_id=$1"+
 					"AND source=$2 AND leftteam=$3 AND rightteam=$4"+
 					"AND winner=$5 AND margin=$6",
-				s.Sources[idx].Round.id,
+				s.Sources[idx].Round.ID,
 				s.Sources[idx].Name,
-				s.Sources[idx].Round.Fixtures[f].leftTeam,
-				s.Sources[idx].Round.Fixtures[f].rightTeam,
-				s.Sources[idx].Round.Fixtures[f].winner,
-				s.Sources[idx].Round.Fixtures[f].margin).Scan(&tmpID)
+				s.Sources[idx].Round.Predictions[f].LeftTeam,
+				s.Sources[idx].Round.Predictions[f].RightTeam,
+				s.Sources[idx].Round.Predictions[f].Winner,
+				s.Sources[idx].Round.Predictions[f].Margin).Scan(&tmpID)
 			switch {
 			case sqlPrdExists == sql.ErrNoRows:
 				// ErrNoRows means we are good to go, execute CopyIn
 				// with round id and fixture parameters
 				_, err = sqlStmt.Exec(
-					s.Sources[idx].Round.id,
+					s.Sources[idx].Round.ID,
 					s.Sources[idx].Name,
-					s.Sources[idx].Round.Fixtures[f].leftTeam,
-					s.Sources[idx].Round.Fixtures[f].rightTeam,
-					s.Sources[idx].Round.Fixtures[f].winner,
-					s.Sources[idx].Round.Fixtures[f].margin,
+					s.Sources[idx].Round.Predictions[f].LeftTeam,
+					s.Sources[idx].Round.Predictions[f].RightTeam,
+					s.Sources[idx].Round.Predictions[f].Winner,
+					s.Sources[idx].Round.Predictions[f].Margin,
 				)
 				if err != nil {
 					return err
@@ -0,0 +1,42 @@
+package sources
+
+import (
+	"brubot/internal/archive"
+	"brubot/internal/domain"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Probe retrieves predictions for the named source from a saved page rather than the source
+// itself, allowing parser configuration to be authored offline. The probed source is returned
+// with its predictions and selector matches (see Matches) alongside any parse error.
+//
+// Sources which are not fetched over http (file, exec) are retrieved as usual.
+func (s *Sources) Probe(name string, roundID int, page archive.Page) (*Source, error) {
+
+	src := s.source(name)
+	if src == nil {
+		return nil, fmt.Errorf("Source: %s is not configured", name)
+	}
+
+	src.Round.ID = roundID
+	src.Round.Predictions = nil
+	src.Client.replay(page)
+
+	err := src.provider.Predictions(context.Background(), src)
+
+	var drift *domain.ParserDriftError
+	if errors.As(err, &drift) && drift.Page == "" {
+		drift.Page = src.Name
+	}
+
+	return src, err
+
+}
+
+// Matches returns selector matches by parser key from the sources last retrieval,
+// nil when the sources provider does not track matches
+func (s *Source) Matches() map[string]int {
+	return s.matches
+}
@@ -0,0 +1,98 @@
+package sources
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Provider retrieves predictions for a single source, populating
+// Source.Round.Predictions with a fixture per predicted match.
+// Providers are called concurrently (one goroutine per source) and should
+// give up once ctx is done.
+type Provider interface {
+	Predictions(ctx context.Context, s *Source) error
+}
+
+// configValidator is optionally implemented by providers able to check
+// a sources parser configuration up front, during Init
+type configValidator interface {
+	Validate(s *Source) error
+}
+
+// fixtureIterator is optionally implemented by providers able to name the parser key and
+// selector (or path) iterating fixtures, reported when a source fails its fixture count check
+type fixtureIterator interface {
+	Iterator(s *Source) (key string, selector string)
+}
+
+// parserDefaulter is optionally implemented by providers with default parser options,
+// allowing options to be read during Init (i.e. a presets legacy round_offset)
+type parserDefaulter interface {
+	Defaults() map[string]string
+}
+
+// ProviderFunc allows an ordinary function to be registered as a Provider
+type ProviderFunc func(ctx context.Context, s *Source) error
+
+// Predictions calls f(ctx, s)
+func (f ProviderFunc) Predictions(ctx context.Context, s *Source) error {
+	return f(ctx, s)
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = make(map[string]Provider)
+)
+
+// Register makes a source provider available by name, names set within
+// config.SourcesConfig are matched against registered providers during Init.
+// Register is expected to be called from a providers init function and panics
+// when called twice with the same name or with a nil provider.
+func Register(name string, provider Provider) {
+
+	providersMu.Lock()
+	defer providersMu.Unlock()
+
+	if provider == nil {
+		panic("sources: Register provider is nil")
+	}
+	if _, dup := providers[name]; dup {
+		panic("sources: Register called twice for provider " + name)
+	}
+	providers[name] = provider
+
+}
+
+// Providers returns a sorted list of the names of all registered providers
+func Providers() []string {
+
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+
+}
+
+// lookupProvider returns the provider registered against name, sources are looked up by
+// Kind falling back to Name for sources registered before kinds were introduced (i.e. VisionAu)
+func lookupProvider(name string) (Provider, error) {
+
+	providersMu.RLock()
+	provider, ok := providers[name]
+	providersMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown source provider: %q (registered providers: %v)", name, Providers())
+	}
+
+	return provider, nil
+
+}
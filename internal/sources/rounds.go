@@ -0,0 +1,110 @@
+package sources
+
+import (
+	"brubot/config"
+	"brubot/internal/helpers"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// roundDateLayout is the layout for dates within a sources rounds.dates mapping
+const roundDateLayout = "2006-01-02"
+
+// roundMap translates our round ID into a sources own round numbering, for tournaments
+// that are not in lock-step with ours. Mappings are checked in order of precedence:
+// an explicit round to round table, a date range lookup, and finally a fixed offset.
+type roundMap struct {
+	offset int         // Added to the round ID when neither the table nor dates match
+	table  map[int]int // Our round ID: source round ID
+	dates  []roundDate // Date ranges mapped to a source round ID
+}
+
+// roundDate maps runs between from and to (inclusive) onto a source round ID
+type roundDate struct {
+	from  time.Time
+	to    time.Time
+	round int
+}
+
+// newRoundMap parses a sources rounds config stanza. legacyOffset is the round_offset
+// parser option which predates rounds, used as the offset where rounds.offset is not set.
+func newRoundMap(rounds config.RoundsConfig, legacyOffset string) (roundMap, error) {
+
+	m := roundMap{
+		table: make(map[int]int),
+	}
+
+	switch {
+	case rounds.Offset != nil:
+		m.offset = *rounds.Offset
+	case legacyOffset != "":
+		offset, err := strconv.Atoi(legacyOffset)
+		if err != nil {
+			return m, fmt.Errorf("invalid round_offset: %w", err)
+		}
+		m.offset = offset
+	}
+
+	for ourRound, sourceRound := range rounds.Table {
+		roundID, err := strconv.Atoi(ourRound)
+		if err != nil {
+			return m, fmt.Errorf("rounds.table key %q is not a round ID", ourRound)
+		}
+		m.table[roundID] = sourceRound
+	}
+
+	for idx, d := range rounds.Dates {
+		from, err := time.Parse(roundDateLayout, d.From)
+		if err != nil {
+			return m, fmt.Errorf("rounds.dates[%d].from: %w", idx, err)
+		}
+		to, err := time.Parse(roundDateLayout, d.To)
+		if err != nil {
+			return m, fmt.Errorf("rounds.dates[%d].to: %w", idx, err)
+		}
+		if to.Before(from) {
+			return m, fmt.Errorf("rounds.dates[%d] ends before it starts", idx)
+		}
+		m.dates = append(m.dates, roundDate{from: from, to: to, round: d.Round})
+	}
+
+	return m, nil
+
+}
+
+// translate returns the source round ID for roundID, date ranges are matched against at
+func (m roundMap) translate(roundID int, at time.Time) int {
+
+	if sourceRound, ok := m.table[roundID]; ok {
+		return sourceRound
+	}
+
+	today, _ := time.Parse(roundDateLayout, at.Format(roundDateLayout))
+	for _, d := range m.dates {
+		if !today.Before(d.from) && !today.After(d.to) {
+			return d.round
+		}
+	}
+
+	return roundID + m.offset
+
+}
+
+// sourceRound returns the current round ID translated to the sources own round numbering,
+// used in place of Round.ID when templating a sources urls and selectors
+func (s *Source) sourceRound() int {
+
+	at := s.clock
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	sourceRound := s.rounds.translate(s.Round.ID, at)
+	if sourceRound != s.Round.ID {
+		helpers.Logger.Debugf("Round translated for source: %s, round: %d, source round: %d", s.Name, s.Round.ID, sourceRound)
+	}
+
+	return sourceRound
+
+}
@@ -0,0 +1,120 @@
+package sources
+
+import (
+	"brubot/config"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// testRounds decodes a rounds config stanza
+func testRounds(t *testing.T, stanza string) config.RoundsConfig {
+
+	t.Helper()
+
+	var rounds config.RoundsConfig
+	if stanza == "" {
+		return rounds
+	}
+	if err := json.Unmarshal([]byte(stanza), &rounds); err != nil {
+		t.Fatal(err)
+	}
+
+	return rounds
+
+}
+
+func TestRoundMapOffset(t *testing.T) {
+
+	tests := []struct {
+		name         string
+		rounds       string
+		legacyOffset string
+		want         int
+	}{
+		{"no mapping", "", "", 12},
+		{"offset", `{"offset": 2}`, "", 14},
+		{"negative offset", `{"offset": -3}`, "", 9},
+		// round_offset predates rounds, i.e. the VisionAu provider default of -3
+		{"legacy offset", "", "-3", 9},
+		{"offset overrides legacy offset", `{"offset": 1}`, "-3", 13},
+		{"zero offset overrides legacy offset", `{"offset": 0}`, "-3", 12},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := newRoundMap(testRounds(t, tt.rounds), tt.legacyOffset)
+			if err != nil {
+				t.Fatalf("newRoundMap: %v", err)
+			}
+			if got := m.translate(12, time.Now()); got != tt.want {
+				t.Errorf("translate(12) = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+}
+
+func TestRoundMapTranslate(t *testing.T) {
+
+	m, err := newRoundMap(testRounds(t, `{
+		"offset": -10,
+		"table": {"12": 1, "13": 2},
+		"dates": [
+			{"from": "2020-07-18", "to": "2020-07-24", "round": 5},
+			{"from": "2020-07-25", "to": "2020-07-25", "round": 6}
+		]
+	}`), "")
+	if err != nil {
+		t.Fatalf("newRoundMap: %v", err)
+	}
+
+	nz := time.FixedZone("NZST", 12*60*60)
+
+	tests := []struct {
+		name    string
+		roundID int
+		at      time.Time
+		want    int
+	}{
+		{"table", 12, time.Date(2020, 7, 20, 12, 0, 0, 0, nz), 1},
+		{"table over dates", 13, time.Date(2020, 7, 20, 12, 0, 0, 0, nz), 2},
+		{"first day from midnight", 20, time.Date(2020, 7, 18, 0, 0, 0, 0, nz), 5},
+		{"day before from", 20, time.Date(2020, 7, 17, 23, 59, 59, 0, nz), 10},
+		{"last day until midnight", 20, time.Date(2020, 7, 24, 23, 59, 59, 0, nz), 5},
+		{"single day range", 20, time.Date(2020, 7, 25, 9, 0, 0, 0, nz), 6},
+		{"day after to", 20, time.Date(2020, 7, 26, 0, 0, 0, 0, nz), 10},
+		// Dates are matched in the local date of at, 17 July UTC is already 18 July in NZ
+		{"local date", 20, time.Date(2020, 7, 17, 20, 0, 0, 0, time.UTC).In(nz), 5},
+		{"utc date", 20, time.Date(2020, 7, 17, 20, 0, 0, 0, time.UTC), 10},
+	}
+
+	for _, tt := range tests {
+		if got := m.translate(tt.roundID, tt.at); got != tt.want {
+			t.Errorf("%s: translate(%d, %s) = %d, want %d", tt.name, tt.roundID, tt.at.Format(time.RFC3339), got, tt.want)
+		}
+	}
+
+}
+
+func TestRoundMapInvalid(t *testing.T) {
+
+	tests := []struct {
+		name         string
+		rounds       string
+		legacyOffset string
+	}{
+		{"legacy offset", "", "minus three"},
+		{"table key", `{"table": {"twelve": 1}}`, ""},
+		{"date from", `{"dates": [{"from": "18/07/2020", "to": "2020-07-24", "round": 5}]}`, ""},
+		{"date to", `{"dates": [{"from": "2020-07-18", "to": "", "round": 5}]}`, ""},
+		{"date order", `{"dates": [{"from": "2020-07-24", "to": "2020-07-18", "round": 5}]}`, ""},
+	}
+
+	for _, tt := range tests {
+		if _, err := newRoundMap(testRounds(t, tt.rounds), tt.legacyOffset); err == nil {
+			t.Errorf("%s: newRoundMap succeeded, want an error", tt.name)
+		}
+	}
+
+}
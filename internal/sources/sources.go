@@ -2,47 +2,122 @@ package sources
 
 import (
 	"brubot/config"
+	"brubot/internal/archive"
+	"brubot/internal/domain"
+	"brubot/internal/teams"
+	"errors"
+	"fmt"
+	"time"
 )
 
 // Sources holds all predictions extracted for each source
 type Sources struct {
 	Sources []Source
+	timeout time.Duration // Overall deadline (seconds) for retrieving predictions from all sources
+	policy  policy        // Partial-failure policy applied once all sources have been retrieved
+	// Strategy used to combine sources predictions for each fixture
+	aggregator Aggregator
+	// Where source weights are taken from (config or db)
+	weightsFrom string
+	// Raw responses are recorded here when set (archiving enabled)
+	Archive *archive.Archive
+	// Set when responses are served from an archived run, backend updates are skipped
+	replaying bool
+	// Target fixtures each source is expected to cover, see Expect
+	expected []domain.Fixture
 }
 
-// Source represents a source data location for margin retrieval.
-type Source struct {
-	Name       string  // Used by reflection call to retrieve margins
-	Tournament string  // Tournament name source is providing margins for
-	Weight     float64 // Used to calculate aggregated margins based on weighted averages
-	Client     client  // Colly client
-	Round      Round   // Current round ID
-}
-
-// Round contains all fixtures and associated prediction per fixture
-// and attempts to mirror target Round for easy translation
-type Round struct {
-	id       int       // ID for a current round, determined by date
-	Fixtures []fixture // All fixutes (matches) within a round/round ID
+// policy determines whether a run can continue when some sources fail
+type policy struct {
+	minSources int      // Minimum number of sources that must return predictions
+	required   []string // Sources that must always return predictions
+	drift      string   // Parser drift policy, a drifted source either fails the run or is dropped
 }
 
-// fixture represents a match within a round
-// and attempts to mirror target fixtures for easy translation
-type fixture struct {
-	leftTeam  string // teamA
-	rightTeam string // teamB
-	winner    string // team name of predicted winning team
-	margin    int    // Point difference for winning team based on prediction
+// Source represents a source data location for margin retrieval.
+type Source struct {
+	Name       string        // Source name, used as the provider name when Kind is not set
+	Kind       string        // Registered provider name used to retrieve margins (i.e. html)
+	Tournament string        // Tournament name source is providing margins for
+	Weight     float64       // Used to calculate aggregated margins based on weighted averages
+	Client     client        // Colly client
+	Round      domain.Round  // Current round ID and predictions per fixture
+	provider   Provider      // Provider registered against Kind (or Name where Kind is unset), set during Init
+	timeout    time.Duration // Per-source deadline (seconds) for retrieving predictions
+	err        error         // Set when predictions could not be retrieved from the source
+	teams      *teams.Registry
+	rounds     roundMap         // Translates Round.ID to the sources own round numbering
+	matches    map[string]int   // Selector matches by parser key for the last retrieval, where tracked by the provider
+	archive    *archive.Archive // Local payloads (file and exec) are recorded here when set, see payload
+	replay     *archive.Replay  // Set when replaying, local payloads are served from the archived run
+	clock      time.Time        // Date based round mappings are resolved at this time when set (replay), otherwise now
 }
 
 // Init builds Sources by iterating through all configured source endpoints within
 // config.SourcesConfig and creating a slice element for each with relevant
-// configurables set.
-func (s *Sources) Init(globalConfig config.GlobalConfig, sourcesConfig config.SourcesConfig) {
+// configurables set. Sources without a registered provider are rejected.
+//
+// A sources provider is looked up by kind, falling back to name for sources
+// predating declarative source kinds (i.e. VisionAotearoa, Asap).
+//
+// Scraped team names are resolved to canonical team IDs through registry.
+func (s *Sources) Init(globalConfig config.GlobalConfig, sourcesConfig config.SourcesConfig, registry *teams.Registry) error {
+
+	var err error
+
+	s.aggregator, err = NewAggregator(sourcesConfig.Aggregation.Strategy, sourcesConfig.Aggregation.Trim)
+	if err != nil {
+		return err
+	}
+
+	switch sourcesConfig.Weights.Source {
+	case "", WeightsFromConfig:
+		s.weightsFrom = WeightsFromConfig
+	case WeightsFromDB:
+		s.weightsFrom = WeightsFromDB
+	default:
+		return fmt.Errorf("Unknown weights source: %q", sourcesConfig.Weights.Source)
+	}
+
+	s.timeout = sourcesConfig.Timeout
+	s.policy = policy{
+		minSources: sourcesConfig.Policy.MinSources,
+		required:   sourcesConfig.Policy.Required,
+	}
+	if s.policy.drift, err = domain.DriftPolicy(sourcesConfig.Policy.Drift); err != nil {
+		return err
+	}
+	// Without a policy every source is required, failing a run on any source failure
+	if s.policy.minSources == 0 {
+		s.policy.minSources = len(sourcesConfig.Sources)
+	}
 
 	for idx := range sourcesConfig.Sources {
 
+		kind := sourcesConfig.Sources[idx].Kind
+		if kind == "" {
+			kind = sourcesConfig.Sources[idx].Name
+		}
+
+		provider, err := lookupProvider(kind)
+		if err != nil {
+			return fmt.Errorf("Failed initialising source: %s: %w", sourcesConfig.Sources[idx].Name, err)
+		}
+
+		// round_offset may be set in config or as a provider default (i.e. VisionAu)
+		legacyOffset := sourcesConfig.Sources[idx].Client.Parser.Predictions["round_offset"]
+		if defaulter, ok := provider.(parserDefaulter); ok && legacyOffset == "" {
+			legacyOffset = defaulter.Defaults()["round_offset"]
+		}
+
+		rounds, err := newRoundMap(sourcesConfig.Sources[idx].Rounds, legacyOffset)
+		if err != nil {
+			return fmt.Errorf("Failed initialising source: %s: %w", sourcesConfig.Sources[idx].Name, err)
+		}
+
 		s.Sources = append(s.Sources, Source{
 			Name:       sourcesConfig.Sources[idx].Name,
+			Kind:       kind,
 			Tournament: sourcesConfig.Sources[idx].Tournament,
 			Weight:     sourcesConfig.Sources[idx].Weight,
 			Client: client{
@@ -58,6 +133,10 @@ func (s *Sources) Init(globalConfig config.GlobalConfig, sourcesConfig config.So
 					predictions: sourcesConfig.Sources[idx].Client.Parser.Predictions,
 				},
 			},
+			provider: provider,
+			timeout:  sourcesConfig.Sources[idx].Timeout,
+			teams:    registry,
+			rounds:   rounds,
 		})
 
 		// Set global parameters where applicable
@@ -69,6 +148,72 @@ func (s *Sources) Init(globalConfig config.GlobalConfig, sourcesConfig config.So
 		// Go ahead an initialise each source endpoints colly client
 		// while initialising the source itself (saves time and money)
 		s.Sources[idx].Client.init()
+
+		// Catch parser misconfiguration before any source is visited
+		if validator, ok := provider.(configValidator); ok {
+			if err := validator.Validate(&s.Sources[idx]); err != nil {
+				return fmt.Errorf("Failed initialising source: %s: %w", s.Sources[idx].Name, err)
+			}
+		}
 	}
 
+	// Required sources must exist, otherwise the policy can never be met
+	for _, name := range s.policy.required {
+		if s.source(name) == nil {
+			return fmt.Errorf("Required source: %s is not configured", name)
+		}
+	}
+
+	return nil
+
+}
+
+// resolver maps a scraped team name to a canonical team ID
+type resolver func(name string) (string, error)
+
+// team resolves a team name scraped from the source to a canonical team ID,
+// aliases scoped to the source name take precedence
+func (s *Source) team(name string) (string, error) {
+
+	if s.teams == nil {
+		return "", errors.New("Source has no team registry, has Init been called?")
+	}
+
+	return s.teams.Resolve(s.Name, name)
+
+}
+
+// source returns the source configured with name, or nil when there is none
+func (s *Sources) source(name string) *Source {
+
+	for idx := range s.Sources {
+		if s.Sources[idx].Name == name {
+			return &s.Sources[idx]
+		}
+	}
+
+	return nil
+
+}
+
+// Replay serves every sources responses from an archived run rather than the network,
+// predictions and margins are not recorded to backend while replaying. Must follow Init.
+//
+// Files and commands are not read or run, their archived payloads are served instead,
+// and rounds are mapped as at the time the archived run started.
+func (s *Sources) Replay(replay *archive.Replay) {
+
+	s.replaying = true
+	for idx := range s.Sources {
+		s.Sources[idx].Client.replay(replay)
+		s.Sources[idx].replay = replay
+		s.Sources[idx].clock = replay.Manifest.Started.Local()
+	}
+
+}
+
+// Expect sets the target fixtures for the round, each source is health checked
+// against the fixtures within its tournament once predictions are retrieved
+func (s *Sources) Expect(fixtures []domain.Fixture) {
+	s.expected = fixtures
 }
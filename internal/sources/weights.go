@@ -0,0 +1,271 @@
+package sources
+
+import (
+	"brubot/internal/helpers"
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Weight sources, set by sources.weights.source
+const (
+	WeightsFromConfig = "config" // weights are taken from each endpoints weight (default)
+	WeightsFromDB     = "db"     // the most recently fitted weights within the weights table are used
+)
+
+// priorFixtures is the number of fixtures a sources fitted weight is shrunk towards the mean by,
+// sources with few scored fixtures (i.e. having missed most rounds) stay close to the average weight
+const priorFixtures = 10
+
+// minFittedWeight floors fitted weights, so a poorly performing source still contributes
+// rather than rounding to 0 (which aggregation treats as unweighted)
+const minFittedWeight = 0.01
+
+// Accuracy holds a sources historical accuracy over a window of rounds, along with the weight derived from it
+type Accuracy struct {
+	Source       string  // Source name
+	Rounds       int     // Rounds within the window the source predicted
+	Missed       int     // Rounds within the window with results but no predictions from the source
+	Fixtures     int     // Predicted fixtures matched to a result
+	Hits         int     // Predicted fixtures where the predicted winner won
+	HitRate      float64 // Hits / Fixtures
+	MeanAbsError float64 // Mean absolute error of the signed margin
+	Weight       float64 // Fitted weight, normalised across all sources
+}
+
+// FitWeights measures each sources winner hit-rate and margin error against results recorded
+// for competition (a target name) over the window rounds prior to roundID, deriving a weight per source.
+//
+// Sources are only scored on fixtures they predicted, so a missed round does not count against a
+// sources accuracy. Instead each sources raw weight (hit-rate / mean absolute error) is shrunk towards
+// the average by how few fixtures it has been scored on, keeping sparse sources near the average.
+func FitWeights(db *sql.DB, competition string, roundID int, window int) ([]Accuracy, error) {
+
+	accuracy := make(map[string]*Accuracy)
+	predicted := make(map[string]map[int]bool)
+	resulted := make(map[int]bool)
+
+	// Latest prediction per source and fixture, matched to results in either team order
+	rows, err := db.Query(
+		"SELECT DISTINCT ON (p.source, p.round_id, p.leftteam, p.rightteam) "+
+			"p.source, p.round_id, r.leftteam, r.rightteam, p.winner, p.margin, r.winner, r.margin "+
+			"FROM predictions p JOIN results r ON r.round_id = p.round_id AND r.competition = $3 "+
+			"AND ((r.leftteam = p.leftteam AND r.rightteam = p.rightteam) "+
+			"OR (r.leftteam = p.rightteam AND r.rightteam = p.leftteam)) "+
+			"WHERE p.round_id >= $1 AND p.round_id < $2 "+
+			"ORDER BY p.source, p.round_id, p.leftteam, p.rightteam, p.id DESC",
+		roundID-window, roundID, competition)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var absErrors = make(map[string]float64)
+
+	for rows.Next() {
+
+		var source, leftTeam, rightTeam, winner, resultWinner string
+		var round, margin, resultMargin int
+
+		if err = rows.Scan(&source, &round, &leftTeam, &rightTeam, &winner, &margin, &resultWinner, &resultMargin); err != nil {
+			return nil, err
+		}
+
+		if _, ok := accuracy[source]; !ok {
+			accuracy[source] = &Accuracy{Source: source}
+			predicted[source] = make(map[int]bool)
+		}
+		predicted[source][round] = true
+
+		predictedMargin := signedResult(leftTeam, rightTeam, winner, margin)
+		actualMargin := signedResult(leftTeam, rightTeam, resultWinner, resultMargin)
+
+		accuracy[source].Fixtures++
+		if sign(predictedMargin) == sign(actualMargin) {
+			accuracy[source].Hits++
+		}
+		absErrors[source] += math.Abs(float64(predictedMargin - actualMargin))
+
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	resultRows, err := db.Query("SELECT DISTINCT round_id FROM results WHERE round_id >= $1 AND round_id < $2 AND competition = $3",
+		roundID-window, roundID, competition)
+	if err != nil {
+		return nil, err
+	}
+	defer resultRows.Close()
+
+	for resultRows.Next() {
+		var round int
+		if err = resultRows.Scan(&round); err != nil {
+			return nil, err
+		}
+		resulted[round] = true
+	}
+	if err = resultRows.Err(); err != nil {
+		return nil, err
+	}
+
+	var fitted []Accuracy
+	var meanRaw float64
+
+	for source := range accuracy {
+		a := accuracy[source]
+		a.Rounds = len(predicted[source])
+		a.Missed = len(resulted) - a.Rounds
+		a.HitRate = float64(a.Hits) / float64(a.Fixtures)
+		a.MeanAbsError = absErrors[source] / float64(a.Fixtures)
+		// Raw weight favours picking winners and penalises margin error,
+		// a perfect margin is floored at 1 point to avoid dividing by 0
+		a.Weight = a.HitRate / math.Max(a.MeanAbsError, 1)
+		meanRaw += a.Weight
+		fitted = append(fitted, *a)
+	}
+
+	if len(fitted) == 0 {
+		return nil, fmt.Errorf("no predictions matched to results for competition: %s between round %d and %d", competition, roundID-window, roundID-1)
+	}
+	meanRaw /= float64(len(fitted))
+
+	var total float64
+	for idx := range fitted {
+		reliability := float64(fitted[idx].Fixtures) / float64(fitted[idx].Fixtures+priorFixtures)
+		fitted[idx].Weight = reliability*fitted[idx].Weight + (1-reliability)*meanRaw
+		total += fitted[idx].Weight
+	}
+	// Raw weights are all 0 where no source picked a winner within the window
+	if total == 0 {
+		return nil, fmt.Errorf("no source predicted a winner between round %d and %d, weights cannot be fitted", roundID-window, roundID-1)
+	}
+	for idx := range fitted {
+		fitted[idx].Weight = math.Max(math.Round(fitted[idx].Weight/total*1000)/1000, minFittedWeight)
+		helpers.Logger.Debugf("Weight fitted for source: %s, rounds: %d, missed: %d, fixtures: %d, hit-rate: %.2f, mean abs error: %.2f, weight: %.3f",
+			fitted[idx].Source,
+			fitted[idx].Rounds,
+			fitted[idx].Missed,
+			fitted[idx].Fixtures,
+			fitted[idx].HitRate,
+			fitted[idx].MeanAbsError,
+			fitted[idx].Weight,
+		)
+	}
+
+	sort.Slice(fitted, func(i, j int) bool { return fitted[i].Source < fitted[j].Source })
+
+	return fitted, nil
+
+}
+
+// signedResult converts a winner and margin into a signed margin, left minus right
+func signedResult(leftTeam string, rightTeam string, winner string, margin int) int {
+
+	switch winner {
+	case leftTeam:
+		return margin
+	case rightTeam:
+		return -margin
+	default:
+		// draw
+		return 0
+	}
+
+}
+
+// UpdateWeights records fitted weights against the round they were fitted for,
+// the latest weights per source are used at runtime with sources.weights.source set to db
+func UpdateWeights(db *sql.DB, roundID int, fitted []Accuracy) error {
+
+	sqlCtx := context.Background()
+	sqlTxn, err := db.BeginTx(sqlCtx, nil)
+	if err != nil {
+		return err
+	}
+	sqlStmt, err := sqlTxn.Prepare(pq.CopyIn("weights", "round_id", "source", "weight", "hit_rate", "mean_abs_error", "fixtures"))
+	if err != nil {
+		return err
+	}
+
+	for _, a := range fitted {
+		if _, err = sqlStmt.Exec(roundID, a.Source, a.Weight, a.HitRate, a.MeanAbsError, a.Fixtures); err != nil {
+			return err
+		}
+	}
+
+	err = sqlStmt.Close()
+	if err != nil {
+		return err
+	}
+
+	return sqlTxn.Commit()
+
+}
+
+// SuggestedYAML renders fitted weights as a sources config snippet
+func SuggestedYAML(fitted []Accuracy) string {
+
+	var b strings.Builder
+
+	b.WriteString("sources:\n  endpoints:\n")
+	for _, a := range fitted {
+		fmt.Fprintf(&b, "    - name: %s\n      weight: %.3f  # hit-rate: %.2f, mean abs error: %.2f, fixtures: %d, missed rounds: %d\n",
+			a.Source, a.Weight, a.HitRate, a.MeanAbsError, a.Fixtures, a.Missed)
+	}
+
+	return b.String()
+
+}
+
+// loadWeights replaces configured source weights with the latest fitted weights. Fitted
+// weights are normalised across the sources they were fitted for, configured weights are
+// not on the same scale so every source must have a fitted weight.
+func (s *Sources) loadWeights(db *sql.DB) error {
+
+	rows, err := db.Query("SELECT DISTINCT ON (source) source, weight FROM weights ORDER BY source, id DESC")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	loaded := make(map[string]bool)
+
+	for rows.Next() {
+
+		var source string
+		var weight float64
+
+		if err = rows.Scan(&source, &weight); err != nil {
+			return err
+		}
+		if src := s.source(source); src != nil {
+			helpers.Logger.Debugf("Fitted weight loaded for source: %s, weight: %.3f (configured: %.3f)", source, weight, src.Weight)
+			src.Weight = weight
+			loaded[source] = true
+		}
+
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	var unfitted []string
+	for idx := range s.Sources {
+		if !loaded[s.Sources[idx].Name] {
+			unfitted = append(unfitted, s.Sources[idx].Name)
+		}
+	}
+	if len(unfitted) > 0 {
+		return fmt.Errorf("No fitted weight for source: %s, fit weights with brubot weights -write or use configured weights",
+			strings.Join(unfitted, ", "))
+	}
+
+	return nil
+
+}
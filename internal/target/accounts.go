@@ -0,0 +1,66 @@
+package target
+
+import (
+	"brubot/config"
+	"fmt"
+)
+
+// Account is a single entrant within a competition (a configured target), every account
+// holds its own session and is authenticated and submitted to independently
+type Account struct {
+	Target
+	Competition string  // Name of the target (competition) entered
+	Strategy    string  // Aggregation strategy for the accounts predictions, empty for the sources default
+	Trim        float64 // Trim for the trimmed_mean strategy
+}
+
+// Accounts creates a target per account configured within targetConfig, targets without
+// accounts are entered by a single account using auth.parameters. Accounts are named
+// <target>/<account>.
+func Accounts(targetConfig config.TargetConfig, env Env) ([]Account, error) {
+
+	if len(targetConfig.Accounts) == 0 {
+		t, err := New(targetConfig, env)
+		if err != nil {
+			return nil, err
+		}
+		return []Account{{Target: t, Competition: targetConfig.Name}}, nil
+	}
+
+	accounts := make([]Account, 0, len(targetConfig.Accounts))
+
+	for idx, account := range targetConfig.Accounts {
+
+		if account.Name == "" {
+			account.Name = fmt.Sprintf("account%d", idx+1)
+		}
+
+		accountConfig := targetConfig
+		accountConfig.Name = targetConfig.Name + "/" + account.Name
+		accountConfig.Accounts = nil
+		// Each account gets its own copy of the credentials
+		accountConfig.Auth.Parameters = make(map[string]string, len(targetConfig.Auth.Parameters)+len(account.Parameters))
+		for param, val := range targetConfig.Auth.Parameters {
+			accountConfig.Auth.Parameters[param] = val
+		}
+		for param, val := range account.Parameters {
+			accountConfig.Auth.Parameters[param] = val
+		}
+
+		t, err := New(accountConfig, env)
+		if err != nil {
+			return nil, err
+		}
+
+		accounts = append(accounts, Account{
+			Target:      t,
+			Competition: targetConfig.Name,
+			Strategy:    account.Aggregation.Strategy,
+			Trim:        account.Aggregation.Trim,
+		})
+
+	}
+
+	return accounts, nil
+
+}
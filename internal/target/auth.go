@@ -5,35 +5,45 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
-	"net/http/cookiejar"
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/PuerkitoBio/goquery"
 )
 
 // all the important target auth stuff
 type auth struct {
-	url            string            // URL string for target authentication
-	parameters     map[string]string // Target credentials strng
-	passwordEncode bool              // Specifies whether to URL encode credential string
-	payload        string            // Holds the auth string built by createPayload()
-	method         string            // http method for auth endpoint
-	userAgent      string            // user agent string set in request header
-	errorMsg       string            // HTML body response string to establish failure
-	timeout        time.Duration     // Auth http client timeout seconds
-	headers        map[string]string // Headers map to set on auth query
-	cookieJar      http.CookieJar    // Returned on successful auth for use by colly
+	url              string            // URL string for target authentication
+	parameters       map[string]string // Target credentials strng
+	preLoginURL      string            // Login page fetched for hidden inputs ahead of authenticating, optional
+	preLoginSelector string            // Selects login page inputs submitted with parameters, defaults to hidden inputs
+	method           string            // http method for auth endpoint
+	userAgent        string            // user agent string set in request header
+	errorMsg         string            // HTML body response string to establish failure
+	timeout          time.Duration     // Auth http client timeout seconds
+	headers          map[string]string // Headers map to set on auth query
+	cookieJar        *sessionJar       // Returned on successful auth for use by colly
+	mode             string            // Auth mode, form (cookie), json (token) or apikey
+	token            token             // Header credentials for token based modes
 }
 
 // Authenticate builds and sends auth string to target and populates
-// a cookiejar to be passed to colly on successful auth.
-func (t *Target) Authenticate() error {
+// a cookiejar to be passed to colly on successful auth. Where sessions are
+// persisted a saved session is reused, and the new session saved otherwise.
+func (t *ajaxTarget) Authenticate() error {
+
+	// A saved session from a previous run is reused rather than logging in again
+	if cookieJar, ok := t.restore(); ok {
+		return t.Client.init(cookieJar)
+	}
 
 	// Call to authenticate method, results in population of auth token
 	// within cookiejar
 	if err := t.Auth.authenticate(t.Auth.timeout); err != nil {
-		return err
+		return &AuthError{Target: t.name, Err: err}
 	}
+	t.persist()
 	// Initialises client with all client specific parameters, passing
 	// auth cookie jar for authenticating subsequent queries.
 	if err := t.Client.init(t.Auth.cookieJar); err != nil {
@@ -44,44 +54,137 @@ func (t *Target) Authenticate() error {
 
 }
 
-// builds auth query, url encoding where required
-func (a *auth) createPayoad() {
+// renew re-authenticates to the target once the clients session has expired, replacing the
+// cookie jar shared by the clients collectors. See session.
+func (t *ajaxTarget) renew() (http.CookieJar, error) {
 
-	if a.passwordEncode {
-		a.parameters["password"] = url.QueryEscape(a.parameters["password"])
+	if err := t.Auth.authenticate(t.Auth.timeout); err != nil {
+		return nil, err
 	}
+	t.Client.collector.SetCookieJar(t.Auth.cookieJar)
+	t.persist()
 
-	// creates a string for authentication from auth.parameters map
-	idx := 0
-	for param, val := range a.parameters {
-		if idx == 0 {
-			a.payload += fmt.Sprintf("%s=%s", param, val)
-		} else {
-			a.payload += fmt.Sprintf("&%s=%s", param, val)
+	return t.Auth.cookieJar, nil
+
+}
+
+// defaultHiddenSelector selects the login page inputs submitted along with credentials
+const defaultHiddenSelector = "input[type=hidden]"
+
+// preLogin fetches the login page using httpClient, returning the named inputs matched by
+// the pre-login selector (i.e. a per-session CSRF token). Cookies set by the login page
+// are kept by the clients cookie jar for authenticating.
+func (a *auth) preLogin(httpClient *http.Client) (url.Values, error) {
+
+	hidden := url.Values{}
+	if a.preLoginURL == "" {
+		return hidden, nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, a.preLoginURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", a.userAgent)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("An invalid response code was received fetching target login page: %d", resp.StatusCode)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing target login page: %w", err)
+	}
+
+	selector := a.preLoginSelector
+	if selector == "" {
+		selector = defaultHiddenSelector
+	}
+	doc.Find(selector).Each(func(_ int, input *goquery.Selection) {
+		if name, ok := input.Attr("name"); ok && name != "" {
+			hidden.Set(name, input.AttrOr("value", ""))
 		}
-		idx++
+	})
+	if len(hidden) == 0 {
+		return nil, fmt.Errorf("no inputs matched %q on target login page", selector)
+	}
+
+	return hidden, nil
+
+}
+
+// form builds the auth form from the login pages hidden inputs with
+// auth.parameters (credentials) set over them
+func (a *auth) form(hidden url.Values) url.Values {
+
+	form := url.Values{}
+	for field, values := range hidden {
+		form[field] = values
+	}
+	for param, val := range a.parameters {
+		form.Set(param, val)
 	}
 
+	return form
+
 }
 
 // Query to target authentication url, using method to submit auth payload
 // Checks response to establish success of authentication attemp
 func (a *auth) authenticate(timeout time.Duration) error {
 
-	a.createPayoad()
-
 	// create a cookieJar to be passed to colly client
-	a.cookieJar, _ = cookiejar.New(nil)
+	a.cookieJar = newSessionJar()
 	// std http client setup
 	httpClient := http.Client{Jar: a.cookieJar, Timeout: time.Second * a.timeout}
 
-	req, err := http.NewRequest(a.method, a.url, strings.NewReader(a.payload))
+	// Token based modes authenticate requests by header rather than cookie, see token.go
+	switch a.mode {
+	case AuthJSON:
+		return a.tokenLogin(&httpClient)
+	case AuthAPIKey:
+		return a.apiKey()
+	}
+
+	hidden, err := a.preLogin(&httpClient)
+	if err != nil {
+		return err
+	}
+
+	// Every field is form encoded
+	respBodyBytes, err := a.send(&httpClient, "application/x-www-form-urlencoded", a.form(hidden).Encode())
 	if err != nil {
 		return err
 	}
 
+	// parse response body for auth failure message and react accordingly
+	if strings.Contains(string(respBodyBytes), a.errorMsg) {
+		return errors.New("Invalid credentials when authenticating to target")
+	}
+
+	return nil
+
+}
+
+// send submits payload to the target authentication url, returning the response body
+// of a successful (200) response. Configured headers take precedence over contentType.
+func (a *auth) send(httpClient *http.Client, contentType string, payload string) ([]byte, error) {
+
+	req, err := http.NewRequest(a.method, a.url, strings.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", contentType)
 	for headerType, headerVal := range a.headers {
-		req.Header.Add(headerType, headerVal)
+		req.Header.Set(headerType, headerVal)
 	}
 
 	// global headers are set separately
@@ -89,26 +192,16 @@ func (a *auth) authenticate(timeout time.Duration) error {
 
 	resp, err := httpClient.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	defer resp.Body.Close()
 
 	// was a 200 response received from auth query
 	if resp.StatusCode != http.StatusOK {
-		return errors.New("An invalid response code was received when authenticating to target")
+		return nil, errors.New("An invalid response code was received when authenticating to target")
 	}
 
-	respBodyBytes, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return err
-	}
-
-	// parse response body for auth failure message and react accordingly
-	if strings.Contains(string(respBodyBytes), a.errorMsg) {
-		return errors.New("Invalid credentials when authenticating to target")
-	}
-
-	return nil
+	return ioutil.ReadAll(resp.Body)
 
 }
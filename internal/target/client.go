@@ -1,6 +1,8 @@
 package target
 
 import (
+	"brubot/internal/archive"
+	"brubot/internal/helpers"
 	"errors"
 	"net"
 	"net/http"
@@ -14,6 +16,15 @@ type client struct {
 	collector *colly.Collector // colly client
 	config    clientConfig     // colly client settings (http/TLS timeouts)
 	parser    clientParser     // identifies fields to be scraped and parsed from target endpoints
+	round     int              // round ID requests are currently being made for (recorded when archiving)
+	matches   map[string]int   // selector matches by parser key for the last page parsed
+	archive   *archive.Archive // raw responses are recorded here when set (archiving enabled)
+	name      string           // target name, responses are archived against
+	// re-authenticates once the session has expired, nil where sessions are not renewed (see session)
+	renew     func() (http.CookieJar, error)
+	loginURLs []string // redirects to these indicate the session has expired
+	// header credentials set on every request for token based auth, see auth.header
+	header func() (name string, value string, expires time.Time)
 }
 
 // Client configuration
@@ -39,8 +50,9 @@ type clientParser struct {
 // Initialise colly client with clientConfig parameters
 func (c *client) init(cookieJar http.CookieJar) error {
 
-	// Creates and configures a colly instance with caching
+	// Creates and configures a colly instance with caching, see scraper
 	if c.config.enableCache {
+		helpers.Logger.Warnf("Caching is not applied to target: %s, pages are always fetched from the target", c.name)
 		c.collector = colly.NewCollector(
 			colly.UserAgent(c.config.userAgent),
 			colly.CacheDir(c.config.cacheDir),
@@ -54,19 +66,26 @@ func (c *client) init(cookieJar http.CookieJar) error {
 	// Sets transport and TLS timeouts,
 	// these may need to be relaxed in brubots config.yaml
 	// if frequent timeouts occur.
-	c.collector.WithTransport(&http.Transport{
+	var transport http.RoundTripper = &http.Transport{
 		DialContext: (&net.Dialer{
 			Timeout: time.Second * c.config.dialTimeout,
 		}).DialContext,
 		TLSHandshakeTimeout: time.Second * c.config.tlsHandShakeTimeout,
-	})
+	}
+	// Expired sessions are renewed and requests replayed without the collector noticing
+	if c.renew != nil {
+		transport = newSession(transport, c)
+	}
+	c.collector.WithTransport(transport)
 
 	c.collector.IgnoreRobotsTxt = c.config.ignoreRobots
+	// Pages such as fixtures are revisited, i.e. to read back current tips
+	c.collector.AllowURLRevisit = true
 
 	// Authentication to target is handled within auth.go
 	// confirm at a minimum the cookie jar housing authentication
 	// token is not empty before setting (we have missed auth in that case).
-	// Note, its still possible the cookie has expired and if so, retry auth.
+	// Expired cookies are handled by the session transport, re-authenticating as required.
 	if cookieJar != nil {
 		c.collector.SetCookieJar(cookieJar)
 	} else {
@@ -76,3 +95,19 @@ func (c *client) init(cookieJar http.CookieJar) error {
 	return nil
 
 }
+
+// scraper returns a collector sharing the clients session (cookies, transport and settings)
+// without any previously registered callbacks, so each page is parsed in isolation.
+// Responses are always fetched from the target, regardless of enableCache.
+func (c *client) scraper() *colly.Collector {
+
+	collector := c.collector.Clone()
+	// Target pages are never served from the cache, they depend on the session and change with
+	// every submission (a cached fixtures page would hide submitted tips and expired sessions)
+	collector.CacheDir = ""
+	// Record target responses when archiving is enabled
+	c.archive.Collect(collector, c.name, func() int { return c.round })
+
+	return collector
+
+}
@@ -0,0 +1,67 @@
+package target
+
+import (
+	"brubot/internal/domain"
+	"fmt"
+)
+
+// Change compares a planned submission against the tip currently held by the target
+type Change struct {
+	Submission
+	Current *domain.Prediction // Tip currently held by the target, nil when untipped or unknown
+	Missing bool               // The fixture has no prediction so nothing will be submitted
+}
+
+// Diff pairs each planned submission with the current tip for its fixture, fixtures
+// without a planned submission are appended as missing
+func Diff(fixtures []domain.Fixture, submissions []Submission, current []domain.Prediction) []Change {
+
+	changes := make([]Change, len(submissions))
+
+	for idx := range submissions {
+		changes[idx].Submission = submissions[idx]
+	}
+
+	for _, fixture := range fixtures {
+		planned := false
+		for idx := range submissions {
+			if submissions[idx].Prediction.Matches(fixture) {
+				planned = true
+				break
+			}
+		}
+		if !planned {
+			changes = append(changes, Change{Submission: Submission{Prediction: domain.Prediction{Fixture: fixture}}, Missing: true})
+		}
+	}
+
+	for idx := range changes {
+		for c := range current {
+			if current[c].Matches(changes[idx].Prediction.Fixture) {
+				changes[idx].Current = &current[c]
+				break
+			}
+		}
+	}
+
+	return changes
+
+}
+
+// Summary describes a change as new, unchanged, winner changed or the change in margin (i.e. +3)
+func (c Change) Summary() string {
+
+	switch {
+	case c.Missing:
+		return "missing"
+	case c.Current == nil:
+		return "new"
+	case c.Current.Winner != c.Prediction.Winner:
+		return "winner changed"
+	case c.Current.Margin == c.Prediction.Margin:
+		return "unchanged"
+	}
+
+	return fmt.Sprintf("%+d", c.Prediction.Margin-c.Current.Margin)
+
+}
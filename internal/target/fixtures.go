@@ -1,6 +1,7 @@
 package target
 
 import (
+	"brubot/internal/domain"
 	"brubot/internal/helpers"
 	"errors"
 	"fmt"
@@ -12,40 +13,48 @@ import (
 
 // Fixtures retrieves all fixtures details within a round based on roundID
 // and populates Round.Fixtures
-func (t *Target) Fixtures(roundID int) error {
+func (t *ajaxTarget) Fixtures(roundID int) ([]domain.Fixture, error) {
 
 	// roundID is determined by the current date within
 	// preset fixtures date range at time of execution
 	t.Round.id = roundID
+	t.Round.Fixtures = nil
 
 	if err := t.getFixtures(); err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return t.fixtures(), nil
 
 }
 
 // getFixtures uses a pre-authenticated client to extract fixture parameters for a specified round.
-func (t *Target) getFixtures() error {
+func (t *ajaxTarget) getFixtures() error {
 
 	var err error
+	collector := t.Client.scraper()
+	// Selector matches, checked for parser drift once the page has been visited
+	counts := make(map[string]int)
 
 	// Scrapes and parses fixtures for the active round (set via t.Round.id).
-	t.Client.collector.OnHTML(t.Client.parser.fixtures["attr_onhtml"], func(e *colly.HTMLElement) {
+	collector.OnHTML(t.Client.parser.fixtures["attr_onhtml"], func(e *colly.HTMLElement) {
+
+		counts["attr_onhtml"]++
 
 		e.ForEach(t.Client.parser.fixtures["attr_fixture"], func(_ int, cl *colly.HTMLElement) {
 
+			counts["attr_fixture"]++
+
 			// Convert teamIDs to int (for better living).
 			leftID, convErr := strconv.Atoi(cl.Attr(t.Client.parser.fixtures["attr_t_leftid"]))
 			if convErr != nil {
-				err = errors.New("failure converting team ID")
+				err = t.driftError("fixtures", t.Client.parser.fixtures, "attr_t_leftid", domain.CheckTeamID, convErr)
 				return
 			}
 
 			rightID, convErr := strconv.Atoi(cl.Attr(t.Client.parser.fixtures["attr_t_rightid"]))
 			if convErr != nil {
-				err = errors.New("failure converting team ID")
+				err = t.driftError("fixtures", t.Client.parser.fixtures, "attr_t_rightid", domain.CheckTeamID, convErr)
 				return
 			}
 
@@ -53,27 +62,45 @@ func (t *Target) getFixtures() error {
 			token := cl.Attr(t.Client.parser.fixtures["attr_token"])
 			// Split left and right teams using known delimeter, assumes
 			// the array returned will consist of only 2 elements being respsective team names
-			leftTeam := helpers.CleanName(strings.Split(cl.Attr(t.Client.parser.fixtures["attr_teams"]),
-				t.Client.parser.fixtures["attr_teams_delimiter"])[0])
-			rightTeam := helpers.CleanName(strings.Split(cl.Attr(t.Client.parser.fixtures["attr_teams"]),
-				t.Client.parser.fixtures["attr_teams_delimiter"])[1])
+			teams := strings.Split(cl.Attr(t.Client.parser.fixtures["attr_teams"]),
+				t.Client.parser.fixtures["attr_teams_delimiter"])
+			if len(teams) != 2 || strings.TrimSpace(teams[0]) == "" || strings.TrimSpace(teams[1]) == "" {
+				err = t.driftError("fixtures", t.Client.parser.fixtures, "attr_teams", domain.CheckTeamName,
+					fmt.Errorf("failure splitting teams: %s", cl.Attr(t.Client.parser.fixtures["attr_teams"])))
+				return
+			}
+			// Resolve scraped team names to canonical team IDs
+			leftTeam, teamErr := t.team(teams[0])
+			if teamErr != nil {
+				err = t.driftError("fixtures", t.Client.parser.fixtures, "attr_teams", domain.CheckKnownTeam, teamErr)
+				return
+			}
+			rightTeam, teamErr := t.team(teams[1])
+			if teamErr != nil {
+				err = t.driftError("fixtures", t.Client.parser.fixtures, "attr_teams", domain.CheckKnownTeam, teamErr)
+				return
+			}
 
 			// Appends a fixture element to a slice of Fixtures
 			// within the active Round, setting scraped and parsed fixture
 			// parameters.
 			t.Round.Fixtures = append(t.Round.Fixtures, fixture{
-				token:     token,
-				leftTeam:  leftTeam,
-				rightTeam: rightTeam,
-				leftID:    leftID,
-				rightID:   rightID,
+				Fixture: domain.Fixture{
+					LeftTeam:   leftTeam,
+					RightTeam:  rightTeam,
+					Tournament: t.tournament(cl.Attr(t.Client.parser.fixtures["attr_tournament"]), leftTeam, rightTeam),
+				},
+				token:   token,
+				leftID:  leftID,
+				rightID: rightID,
 				// Initialise winnerID to -1 to later detect missed predictions,
 				// a draw is reflected with a winnerID of 0 and margin of 0
 				winnerID: -1,
 			})
 
-			helpers.Logger.Debugf("Fixture has been retrieved for round: %d, token: %s leftTeam: %s (id: %d), rightTeam: %s (id %d)",
+			helpers.Logger.Debugf("Fixture has been retrieved for round: %d, tournament: %s, token: %s leftTeam: %s (id: %d), rightTeam: %s (id %d)",
 				t.Round.id,
+				t.Round.Fixtures[len(t.Round.Fixtures)-1].Tournament,
 				token,
 				leftTeam,
 				leftID,
@@ -86,20 +113,29 @@ func (t *Target) getFixtures() error {
 	})
 
 	// If the login attribute is detected in the response body, authentication has somehow failed
-	t.Client.collector.OnHTML(t.Client.parser.login["attr_login"], func(e *colly.HTMLElement) {
-		err = errors.New("An error occurred during fixture extraction, client is not authenticated")
+	collector.OnHTML(t.Client.parser.login["attr_login"], func(e *colly.HTMLElement) {
+		err = &AuthError{Target: t.name, Err: errors.New("An error occurred during fixture extraction, client is not authenticated")}
 		return
 	})
 
 	// Client error has occurred attempting .Visit
-	t.Client.collector.OnError(func(r *colly.Response, resError error) {
+	collector.OnError(func(r *colly.Response, resError error) {
 		helpers.Logger.Errorf("An error occurred during fixture extraction, client response %+v URL %s error %s", r, r.Request.URL, resError)
-		err = fmt.Errorf("An error occurred during fixture extraction, client response %+v URL %s error %s", r, r.Request.URL, resError)
+		err = fmt.Errorf("An error occurred during fixture extraction, client response %+v URL %s error %w", r, r.Request.URL, resError)
 		return
 	})
 
 	// Client request to the targets fixture endpoint based on the currently active round.
-	t.Client.collector.Visit(fmt.Sprint(t.Client.config.urls["fixtures"], t.Round.id))
+	t.Client.round = t.Round.id
+	collector.Visit(fmt.Sprint(t.Client.config.urls["fixtures"], t.Round.id))
+
+	t.Client.matches = counts
+	helpers.Logger.Debugf("Selector matches for fixtures: %v", counts)
+
+	// Without fixtures there is nothing to submit, fixture drift always fails a run
+	if err == nil {
+		err = t.checkMatches("fixtures", t.Client.parser.fixtures, counts, "attr_onhtml", "attr_fixture")
+	}
 
 	return err
 
@@ -1,84 +1,93 @@
 package target
 
 import (
+	"brubot/internal/domain"
 	"brubot/internal/helpers"
 	"errors"
 	"fmt"
 	"net/url"
 
 	"github.com/gocolly/colly/v2"
-	"github.com/lithammer/fuzzysearch/fuzzy"
 )
 
-// Predictions handles mapping predictions to fixtures, sets winnerID and margin fields
-// for matched fixtures and calls client with predictions for submission to target.
-func (t *Target) Predictions(predictions map[string]int) error {
+// Submit plans the submission of predictions (see Plan) and calls client with each
+// submission for the target. Saved tips are then read back and compared against each
+// submission, mismatches are resubmitted up to the targets verify retries before being
+// reported. Fixtures without a matching prediction are reported as missing once every
+// other prediction has been submitted.
+func (t *ajaxTarget) Submit(predictions []domain.Prediction) ([]Confirmation, error) {
 
-	// predictions are expected to be in the format winningTeamName: margin
-	for team, margin := range predictions {
+	submissions, err := t.Plan(predictions)
 
-		// Strip out any article and trailing whitespace from team name
-		team = helpers.CleanName(team)
+	// Call to client to set matched predictions for each fixture
+	if setErr := t.setPredictions(submissions); setErr != nil {
+		return nil, setErr
+	}
 
-		for idx := range t.Round.Fixtures {
-			// fuzzy.RankMatchNormalizedFold provides string matching with Unicode normalisation,
-			// where 0 is an exact match, and greater than 0 means less matching characters at higher values.
-			// Naively using a scoring of 0 or greater as team name matching criteria.
+	confirmations, verifyErr := verify(submissions, t.CurrentTips, t.setPredictions, t.verifyRetries)
+	if verifyErr != nil {
+		return confirmations, verifyErr
+	}
 
-			// Sets Fixture teamID as the winnerID and margin when either a left or right
-			// team in the fixture matches with the predictions's winning team.
-			// TeamIDs are retrieved from the target and are randomish/too inconsistent to map up front.
-			if fuzzy.RankMatchNormalizedFold(team, t.Round.Fixtures[idx].leftTeam) >= 0 {
-				if margin == 0 {
-					// Indicates fixture prediction is a draw (margin = 0 / winner_id = 0)
-					t.Round.Fixtures[idx].winnerID = 0
-				} else {
-					t.Round.Fixtures[idx].winnerID = t.Round.Fixtures[idx].leftID
-				}
-
-				t.Round.Fixtures[idx].margin = margin
-				helpers.Logger.Debugf("Prediction has been set: leftTeam: %s winnerID: %d margin: %d, token: %s",
-					t.Round.Fixtures[idx].leftTeam,
-					t.Round.Fixtures[idx].winnerID,
-					t.Round.Fixtures[idx].margin,
-					t.Round.Fixtures[idx].token,
-				)
-				break
-			}
+	return confirmations, err
 
-			if fuzzy.RankMatchNormalizedFold(team, t.Round.Fixtures[idx].rightTeam) >= 0 {
-				if margin == 0 {
-					t.Round.Fixtures[idx].winnerID = 0
-				} else {
-					t.Round.Fixtures[idx].winnerID = t.Round.Fixtures[idx].rightID
-				}
+}
 
-				t.Round.Fixtures[idx].margin = margin
-				helpers.Logger.Debugf("Prediction has been set: rightTeam: %s winnerID: %d margin: %d, token: %s",
-					t.Round.Fixtures[idx].rightTeam,
-					t.Round.Fixtures[idx].winnerID,
-					t.Round.Fixtures[idx].margin,
-					t.Round.Fixtures[idx].token,
-				)
-				break
+// Plan handles mapping predictions to fixtures, sets winnerID and margin fields
+// for matched fixtures and builds the submission query for each, without sending anything.
+//
+// Predictions are matched to fixtures by their pair of canonical team IDs (in either order),
+// fixtures without a matching prediction are reported as missing.
+func (t *ajaxTarget) Plan(predictions []domain.Prediction) ([]Submission, error) {
+
+	for idx := range t.Round.Fixtures {
+
+		// Plans start afresh, fixtures are only submitted with predictions from this plan
+		t.Round.Fixtures[idx].winnerID = -1
+		t.Round.Fixtures[idx].margin = 0
+
+		for _, prediction := range predictions {
+
+			if !t.Round.Fixtures[idx].Matches(prediction.Fixture) {
+				continue
 			}
 
-		}
-	}
+			// Sets Fixture teamID as the winnerID and margin based on the predicted winner.
+			// TeamIDs are retrieved from the target and are randomish/too inconsistent to map up front.
+			switch prediction.Winner {
+			case domain.Draw:
+				// Indicates fixture prediction is a draw (margin = 0 / winner_id = 0)
+				t.Round.Fixtures[idx].winnerID = 0
+			case t.Round.Fixtures[idx].LeftTeam:
+				t.Round.Fixtures[idx].winnerID = t.Round.Fixtures[idx].leftID
+			case t.Round.Fixtures[idx].RightTeam:
+				t.Round.Fixtures[idx].winnerID = t.Round.Fixtures[idx].rightID
+			default:
+				return nil, fmt.Errorf("Prediction for fixture %s has an invalid winner: %s", prediction.Fixture, prediction.Winner)
+			}
 
-	// Call to client to set matched predictions for each fixture
-	if err := t.setPredictions(); err != nil {
-		return err
+			t.Round.Fixtures[idx].margin = prediction.Margin
+			helpers.Logger.Debugf("Prediction has been set: fixture: %s winner: %s winnerID: %d margin: %d, token: %s",
+				t.Round.Fixtures[idx].Fixture,
+				prediction.Winner,
+				t.Round.Fixtures[idx].winnerID,
+				t.Round.Fixtures[idx].margin,
+				t.Round.Fixtures[idx].token,
+			)
+			break
+
+		}
 	}
 
-	return nil
+	return t.submissions()
 
 }
 
-// setPredictions uses a pre-authenticated client to submit predictions for each fixture to the target.
-func (t *Target) setPredictions() error {
+// submissions builds the prediction query for each fixture with a prediction set
+func (t *ajaxTarget) submissions() ([]Submission, error) {
 
 	var err error
+	var submissions []Submission
 
 	for idx := range t.Round.Fixtures {
 
@@ -89,20 +98,22 @@ func (t *Target) setPredictions() error {
 			if err == nil {
 				// No need to wrap err on the first missed prediction
 				err = fmt.Errorf("An error has occurred due to a missing predictions, fixture: %s v %s with token %s",
-					t.Round.Fixtures[idx].leftTeam,
-					t.Round.Fixtures[idx].rightTeam,
+					t.Round.Fixtures[idx].LeftTeam,
+					t.Round.Fixtures[idx].RightTeam,
 					t.Round.Fixtures[idx].token)
 			} else {
 				err = fmt.Errorf("%w, fixture %s v %s with token %s",
-					err, t.Round.Fixtures[idx].leftTeam,
-					t.Round.Fixtures[idx].rightTeam,
+					err, t.Round.Fixtures[idx].LeftTeam,
+					t.Round.Fixtures[idx].RightTeam,
 					t.Round.Fixtures[idx].token)
 			}
-		} else {
-			// Submit parsed prediction query string to target, only token needs escaping at present.
-			// This has to be done separately for each fixture (i.e. within the fixture loop) due to the
-			// old school AJAX post mechanism used by the target.
-			t.Client.collector.Visit(fmt.Sprint(t.Client.config.urls["predictions"],
+			continue
+		}
+
+		// Parsed prediction query string for the target, only token needs escaping at present.
+		submissions = append(submissions, Submission{
+			Prediction: t.Round.Fixtures[idx].prediction(),
+			Request: fmt.Sprint(t.Client.config.urls["predictions"],
 				fmt.Sprintf(t.Client.parser.predictions["attr_prediction"],
 					url.QueryEscape(t.Round.Fixtures[idx].token),
 					t.Round.Fixtures[idx].winnerID,
@@ -111,34 +122,67 @@ func (t *Target) setPredictions() error {
 					t.Round.Fixtures[idx].margin,
 					t.Round.Fixtures[idx].winnerID,
 					t.Round.Fixtures[idx].margin),
-			))
-			helpers.Logger.Debugf("Prediction has been submitted for round: %d, winnerID: %d, "+
-				"leftTeam: %s, leftID: %d, rightTeam: %s, rightID: %d, margin: %d, token: %s",
-				t.Round.id,
-				t.Round.Fixtures[idx].winnerID,
-				t.Round.Fixtures[idx].leftTeam,
-				t.Round.Fixtures[idx].leftID,
-				t.Round.Fixtures[idx].rightTeam,
-				t.Round.Fixtures[idx].rightID,
-				t.Round.Fixtures[idx].margin,
-				t.Round.Fixtures[idx].token,
-			)
-		}
+			),
+		})
+
+	}
+
+	return submissions, err
+
+}
+
+// prediction returns the prediction set for a fixture
+func (f *fixture) prediction() domain.Prediction {
+
+	p := domain.Prediction{Fixture: f.Fixture, Winner: domain.Draw, Margin: f.margin}
+	switch f.winnerID {
+	case 0:
+		p.Margin = 0
+	case f.leftID:
+		p.Winner = f.LeftTeam
+	case f.rightID:
+		p.Winner = f.RightTeam
 	}
 
+	return p
+
+}
+
+// setPredictions uses a pre-authenticated client to submit predictions for each fixture to the target.
+func (t *ajaxTarget) setPredictions(submissions []Submission) error {
+
+	var err error
+	collector := t.Client.scraper()
+
+	t.Client.round = t.Round.id
+
+	// Handlers are registered ahead of visiting, so every submission response is checked.
 	// If the login attribute is detected in the response body, authentication has somehow failed
-	t.Client.collector.OnHTML(t.Client.parser.login["attr_login"], func(e *colly.HTMLElement) {
-		err = errors.New("An error occurred during prediction submission, client is not authenticated")
-		return
+	collector.OnHTML(t.Client.parser.login["attr_login"], func(e *colly.HTMLElement) {
+		err = &AuthError{Target: t.name, Err: errors.New("An error occurred during prediction submission, client is not authenticated")}
 	})
 
 	// Client error has occurred attempting .Visit
-	t.Client.collector.OnError(func(r *colly.Response, resError error) {
+	collector.OnError(func(r *colly.Response, resError error) {
 		helpers.Logger.Errorf("An error occurred during prediction submission, client response %+v URL %s error %s", r, r.Request.URL, resError)
-		err = fmt.Errorf("An error occurred during prediction submission, client response %+v URL %s error %s", r, r.Request.URL, resError)
-		return
+		err = fmt.Errorf("An error occurred during prediction submission, client response %+v URL %s error %w", r, r.Request.URL, resError)
 	})
 
+	for _, submission := range submissions {
+
+		// This has to be done separately for each fixture (i.e. within the fixture loop) due to the
+		// old school AJAX post mechanism used by the target.
+		collector.Visit(submission.Request)
+		helpers.Logger.Debugf("Prediction has been submitted for round: %d, fixture: %s, winner: %s, margin: %d, request: %s",
+			t.Round.id,
+			submission.Prediction.Fixture,
+			submission.Prediction.Winner,
+			submission.Prediction.Margin,
+			submission.Request,
+		)
+
+	}
+
 	return err
 
 }
@@ -0,0 +1,37 @@
+package target
+
+import (
+	"brubot/internal/archive"
+	"fmt"
+)
+
+// Pages which may be probed
+const (
+	ProbeFixtures = "fixtures"
+	ProbeResults  = "results"
+)
+
+// Probe parses a saved fixtures or results page rather than the target, allowing parser
+// configuration to be authored offline, roundID is the round of the page.
+func (t *ajaxTarget) Probe(page string, roundID int, saved archive.Page) (ProbeResult, error) {
+
+	var err error
+
+	if err = t.offline(saved); err != nil {
+		return ProbeResult{}, err
+	}
+
+	switch page {
+	case ProbeFixtures:
+		t.Round.id = roundID
+		err = t.getFixtures()
+	case ProbeResults:
+		t.PreviousRound.ID = roundID
+		err = t.getResults()
+	default:
+		return ProbeResult{}, fmt.Errorf("Unknown target page: %q, expected %s or %s", page, ProbeFixtures, ProbeResults)
+	}
+
+	return ProbeResult{Fixtures: t.fixtures(), Results: t.PreviousRound.Results, Matches: t.Client.matches}, err
+
+}
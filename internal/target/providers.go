@@ -0,0 +1,142 @@
+package target
+
+import (
+	"brubot/config"
+	"brubot/internal/archive"
+	"brubot/internal/domain"
+	"brubot/internal/teams"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrCurrentTipsUnsupported is returned by CurrentTips where a target is unable
+// to report the predictions it currently holds
+var ErrCurrentTipsUnsupported = errors.New("target does not support retrieving current tips")
+
+// Target is a tipping competition predictions are submitted to. Each competition
+// is implemented by a provider registered against a kind (see Register) and selected
+// through config, any number of targets may be submitted to within a run.
+type Target interface {
+	// Name returns the targets configured name
+	Name() string
+	// Authenticate establishes a session with the target
+	Authenticate() error
+	// Fixtures retrieves the fixtures within a round, the round is retained for
+	// subsequent calls to Submit and CurrentTips
+	Fixtures(roundID int) ([]domain.Fixture, error)
+	// Results retrieves the results of completed fixtures within a round
+	Results(roundID int) ([]domain.Result, error)
+	// Plan builds the submission of predictions for the fixtures last retrieved with Fixtures
+	// without sending anything, allowing a dry run
+	Plan(predictions []domain.Prediction) ([]Submission, error)
+	// Submit submits predictions for the fixtures last retrieved with Fixtures, confirming
+	// each submission against the tips saved by the target where possible
+	Submit(predictions []domain.Prediction) ([]Confirmation, error)
+	// CurrentTips retrieves the predictions currently held by the target for the
+	// fixtures last retrieved with Fixtures
+	CurrentTips() ([]domain.Prediction, error)
+}
+
+// Submission is a single prediction as it is submitted to a target
+type Submission struct {
+	Prediction domain.Prediction // Prediction oriented as the targets fixture
+	Request    string            // URL or payload sent to the target
+}
+
+// Replayer is optionally implemented by targets able to serve responses from an
+// archived run, Replay is called in place of Authenticate
+type Replayer interface {
+	Replay(replay *archive.Replay) error
+}
+
+// Prober is optionally implemented by targets able to parse a saved page,
+// used when authoring parser configuration offline
+type Prober interface {
+	Probe(page string, roundID int, saved archive.Page) (ProbeResult, error)
+}
+
+// ProbeResult holds fixtures or results parsed from a saved page
+// along with selector matches by parser key
+type ProbeResult struct {
+	Fixtures []domain.Fixture
+	Results  []domain.Result
+	Matches  map[string]int
+}
+
+// Env holds everything shared by targets within a run
+type Env struct {
+	Global  config.GlobalConfig
+	Teams   *teams.Registry  // Scraped team names are resolved to canonical team IDs
+	Archive *archive.Archive // Raw responses are recorded here when set (archiving enabled)
+}
+
+// Factory creates a target from its configuration
+type Factory func(targetConfig config.TargetConfig, env Env) (Target, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = make(map[string]Factory)
+)
+
+// Register makes a target provider available by kind, kinds set within
+// config.TargetConfig are matched against registered providers by New.
+// Register is expected to be called from a providers init function and panics
+// when called twice with the same kind or with a nil factory.
+func Register(kind string, factory Factory) {
+
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	if factory == nil {
+		panic("target: Register factory is nil")
+	}
+	if _, dup := factories[kind]; dup {
+		panic("target: Register called twice for kind " + kind)
+	}
+	factories[kind] = factory
+
+}
+
+// Kinds returns a sorted list of all registered target kinds
+func Kinds() []string {
+
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	kinds := make([]string, 0, len(factories))
+	for kind := range factories {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	return kinds
+
+}
+
+// New creates a target using the provider registered against its kind,
+// targets without a kind use KindAjax
+func New(targetConfig config.TargetConfig, env Env) (Target, error) {
+
+	kind := targetConfig.Kind
+	if kind == "" {
+		kind = KindAjax
+	}
+
+	factoriesMu.RLock()
+	factory, ok := factories[kind]
+	factoriesMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("Failed initialising target: %s: unknown kind %q (registered: %v)", targetConfig.Name, kind, Kinds())
+	}
+
+	t, err := factory(targetConfig, env)
+	if err != nil {
+		return nil, fmt.Errorf("Failed initialising target: %s: %w", targetConfig.Name, err)
+	}
+
+	return t, nil
+
+}
@@ -1,6 +1,7 @@
 package target
 
 import (
+	"brubot/internal/domain"
 	"brubot/internal/helpers"
 	"context"
 	"database/sql"
@@ -13,75 +14,107 @@ import (
 	"github.com/lib/pq"
 )
 
-// Results retrieves the completed fixture results for a round,
-// and updates backend accordingly
-func (t *Target) Results(previousRoundID int, db *sql.DB) error {
+// Results retrieves the completed fixture results for a round
+func (t *ajaxTarget) Results(previousRoundID int) ([]domain.Result, error) {
 
 	// roundID *should* typically be currentRound - 1 for retrieving
 	// the previous rounds fixture results
-	t.PreviousRound.id = previousRoundID
-	if err := t.getResults(); err != nil {
-		return err
-	}
+	t.PreviousRound.ID = previousRoundID
+	t.PreviousRound.Results = nil
 
-	if err := t.updateResults(db); err != nil {
-		return err
+	if err := t.getResults(); err != nil {
+		// Results only feed source weighting, so drifted results may be skipped by policy
+		var drift *domain.ParserDriftError
+		if errors.As(err, &drift) && t.driftPolicy == domain.DriftDegrade {
+			helpers.Logger.Warn("Continuing without results as drift policy allows: ", err)
+			t.PreviousRound.Results = nil
+			return nil, nil
+		}
+		return nil, err
 	}
 
-	return nil
+	return t.PreviousRound.Results, nil
 
 }
 
 // getResults uses a pre-authenticated client to retrieve fixture results from a specified round
-func (t *Target) getResults() error {
+func (t *ajaxTarget) getResults() error {
 
 	var err error
 	var margin int
 	var winner string
+	collector := t.Client.scraper()
+	// Selector matches, checked for parser drift once the page has been visited
+	counts := make(map[string]int)
+
+	collector.OnHTML(fmt.Sprintf(t.Client.parser.results["attr_onhtml"], t.PreviousRound.ID), func(e *colly.HTMLElement) {
 
-	t.Client.collector.OnHTML(fmt.Sprintf(t.Client.parser.results["attr_onhtml"], t.PreviousRound.id), func(e *colly.HTMLElement) {
+		counts["attr_onhtml"]++
 
 		e.ForEach(t.Client.parser.results["attr_fixture"], func(_ int, cl *colly.HTMLElement) {
 
+			counts["attr_fixture"]++
+
 			// Split leftTeam and rightTeam based into array using a known delimeter for
 			// team name differentiation. We are assuming that there will always be 2 elements
 			// in the array returned from the split, 0 being leftTeam and 1 being rightTeam.
-			leftTeam := helpers.CleanName(strings.Split(
-				cl.Attr(t.Client.parser.results["attr_t_teams"]),
-				t.Client.parser.results["attr_t_teams_delimiter"],
-			)[0])
-			rightTeam := helpers.CleanName(strings.Split(
+			teams := strings.Split(
 				cl.Attr(t.Client.parser.results["attr_t_teams"]),
 				t.Client.parser.results["attr_t_teams_delimiter"],
-			)[1])
+			)
+			if len(teams) != 2 || strings.TrimSpace(teams[0]) == "" || strings.TrimSpace(teams[1]) == "" {
+				err = t.driftError("results", t.Client.parser.results, "attr_t_teams", domain.CheckTeamName,
+					fmt.Errorf("failure splitting teams: %s", cl.Attr(t.Client.parser.results["attr_t_teams"])))
+				return
+			}
+			// Resolve scraped team names to canonical team IDs
+			leftTeam, teamErr := t.team(teams[0])
+			if teamErr != nil {
+				err = t.driftError("results", t.Client.parser.results, "attr_t_teams", domain.CheckKnownTeam, teamErr)
+				return
+			}
+			rightTeam, teamErr := t.team(teams[1])
+			if teamErr != nil {
+				err = t.driftError("results", t.Client.parser.results, "attr_t_teams", domain.CheckKnownTeam, teamErr)
+				return
+			}
 			// If the results parser returns a draw then set margin to 0 and winner to draw
 			if strings.EqualFold(cl.ChildText(t.Client.parser.results["attr_t_results"]), t.Client.parser.results["attr_t_draw"]) {
 				margin = 0
-				winner = "draw"
+				winner = domain.Draw
 			} else {
 				// Split the winner and margin based on a known delimeter for winner team name
 				// and margin
-				winner = helpers.CleanName(strings.Split(
+				result := strings.Split(
 					cl.ChildText(t.Client.parser.results["attr_t_results"]),
 					t.Client.parser.results["attr_t_winner_delimiter"],
-				)[0])
+				)
+				if len(result) != 2 {
+					err = t.driftError("results", t.Client.parser.results, "attr_t_results", domain.CheckMargin,
+						fmt.Errorf("failure splitting result: %s", cl.ChildText(t.Client.parser.results["attr_t_results"])))
+					return
+				}
+				if winner, teamErr = t.team(result[0]); teamErr != nil {
+					err = t.driftError("results", t.Client.parser.results, "attr_t_results", domain.CheckKnownTeam, teamErr)
+					return
+				}
 				// Sets and converts margin from string to int
-				if marginResult, marginErr := strconv.Atoi(
-					strings.Split(
-						cl.ChildText(t.Client.parser.results["attr_t_results"]),
-						t.Client.parser.results["attr_t_winner_delimiter"])[1],
-				); marginErr != nil {
-					err = marginErr
-				} else {
-					margin = marginResult
+				marginResult, marginErr := strconv.Atoi(strings.TrimSpace(result[1]))
+				if marginErr != nil {
+					err = t.driftError("results", t.Client.parser.results, "attr_t_results", domain.CheckMargin, marginErr)
+					return
 				}
+				margin = marginResult
 			}
 			// Append extracted result to previousRounds Results
-			t.PreviousRound.Results = append(t.PreviousRound.Results, result{
-				leftTeam:  leftTeam,
-				rightTeam: rightTeam,
-				winner:    winner,
-				margin:    margin,
+			t.PreviousRound.Results = append(t.PreviousRound.Results, domain.Result{
+				Fixture: domain.Fixture{
+					LeftTeam:   leftTeam,
+					RightTeam:  rightTeam,
+					Tournament: t.tournament(cl.Attr(t.Client.parser.results["attr_tournament"]), leftTeam, rightTeam),
+				},
+				Winner: winner,
+				Margin: margin,
 			})
 
 			helpers.Logger.Debugf("Result has been retrieved, leftTeam: %s, rightTeam: %s, winner: %s, margin: %d",
@@ -96,27 +129,36 @@ func (t *Target) getResults() error {
 	})
 
 	// If the login attribute is detected in the response body, authentication has somehow failed
-	t.Client.collector.OnHTML(t.Client.parser.login["attr_login"], func(e *colly.HTMLElement) {
-		err = errors.New("An error occurred during results retrieval, client is not authenticated")
+	collector.OnHTML(t.Client.parser.login["attr_login"], func(e *colly.HTMLElement) {
+		err = &AuthError{Target: t.name, Err: errors.New("An error occurred during results retrieval, client is not authenticated")}
 		return
 	})
 
 	// Client error has occurred attempting .Visit
-	t.Client.collector.OnError(func(r *colly.Response, resError error) {
+	collector.OnError(func(r *colly.Response, resError error) {
 		helpers.Logger.Errorf("An error occurred results retrieval, client response %+v URL %s error %s", r, r.Request.URL, resError)
-		err = fmt.Errorf("An error occurred during results retrieval, client response %+v URL %s error %s", r, r.Request.URL, resError)
+		err = fmt.Errorf("An error occurred during results retrieval, client response %+v URL %s error %w", r, r.Request.URL, resError)
 		return
 	})
 
 	// Client request to the targets results endpoint based on the results roundID
-	t.Client.collector.Visit(fmt.Sprintf(t.Client.config.urls["results"], t.PreviousRound.id, t.PreviousRound.id))
+	t.Client.round = t.PreviousRound.ID
+	collector.Visit(fmt.Sprintf(t.Client.config.urls["results"], t.PreviousRound.ID, t.PreviousRound.ID))
+
+	t.Client.matches = counts
+	helpers.Logger.Debugf("Selector matches for results: %v", counts)
+
+	if err == nil {
+		err = t.checkMatches("results", t.Client.parser.results, counts, "attr_onhtml", "attr_fixture")
+	}
 
 	return err
 
 }
 
-// updateResults writes retrieved results for a previous round of fixtures to backend
-func (t *Target) updateResults(db *sql.DB) error {
+// UpdateResults writes results retrieved from a target for a previous round of fixtures to backend,
+// results are recorded against the competition (target name) they were retrieved from
+func UpdateResults(db *sql.DB, competition string, roundID int, results []domain.Result) error {
 
 	// backend updates could become their own abstraction as I only use CopyIn
 	// and do some level of duplicate checking to prevent duplicate prediction/results updates
@@ -131,7 +173,7 @@ func (t *Target) updateResults(db *sql.DB) error {
 		return err
 	}
 	// prepare results update with COPY FROM (table, fields[..])
-	sqlStmt, err := sqlTxn.Prepare(pq.CopyIn("results", "round_id", "leftteam", "rightteam", "winner", "margin"))
+	sqlStmt, err := sqlTxn.Prepare(pq.CopyIn("results", "competition", "round_id", "leftteam", "rightteam", "winner", "margin"))
 	if err != nil {
 		return err
 	}
@@ -139,28 +181,30 @@ func (t *Target) updateResults(db *sql.DB) error {
 	// looking *very* familiar...will abstract when it makes sense.
 	helpers.Logger.Debug("Results update is emminent, hold tight...")
 
-	for idx := range t.PreviousRound.Results {
+	for idx := range results {
 		// Same "Ugly Check" as source prediction update
 		sqlPrdExists := db.QueryRowContext(sqlCtx,
-			"SELECT id FROM results WHERE round_id=$1"+
-				"AND leftteam=$2 AND rightteam=$3"+
-				"AND winner=$4 AND margin=$5",
-			t.PreviousRound.id,
-			t.PreviousRound.Results[idx].leftTeam,
-			t.PreviousRound.Results[idx].rightTeam,
-			t.PreviousRound.Results[idx].winner,
-			t.PreviousRound.Results[idx].margin).Scan(&tmpID)
+			"SELECT id FROM results WHERE competition=$1 AND round_id=$2 "+
+				"AND leftteam=$3 AND rightteam=$4 "+
+				"AND winner=$5 AND margin=$6",
+			competition,
+			roundID,
+			results[idx].LeftTeam,
+			results[idx].RightTeam,
+			results[idx].Winner,
+			results[idx].Margin).Scan(&tmpID)
 
 		switch {
 		case sqlPrdExists == sql.ErrNoRows:
 			// ErrNoRows means we are good to go, execute CopyIn
 			// with PreviousRound id and results
 			_, err = sqlStmt.Exec(
-				t.PreviousRound.id,
-				t.PreviousRound.Results[idx].leftTeam,
-				t.PreviousRound.Results[idx].rightTeam,
-				t.PreviousRound.Results[idx].winner,
-				t.PreviousRound.Results[idx].margin,
+				competition,
+				roundID,
+				results[idx].LeftTeam,
+				results[idx].RightTeam,
+				results[idx].Winner,
+				results[idx].Margin,
 			)
 			if err != nil {
 				return err
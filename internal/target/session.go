@@ -0,0 +1,209 @@
+package target
+
+import (
+	"brubot/internal/helpers"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// AuthError is returned when a target cannot be authenticated, either initially or
+// when renewing an expired session
+type AuthError struct {
+	Target string // Target name, see config.TargetConfig
+	Err    error
+}
+
+func (e *AuthError) Error() string {
+	return fmt.Sprintf("Authentication to target: %s failed: %v", e.Target, e.Err)
+}
+
+func (e *AuthError) Unwrap() error {
+	return e.Err
+}
+
+// session renews an expired target session transparently to the clients collector.
+//
+// A session has expired when the target responds with a 401, redirects to a login URL
+// or returns its login page (identified by the login parsers attr_login). The target is
+// re-authenticated and the request is replayed once with the renewed session cookies,
+// a session which is still expired after renewal fails the request with an AuthError.
+//
+// Token based sessions set the token header on every request, tokens with a known
+// expiry are renewed ahead of expiring.
+type session struct {
+	transport http.RoundTripper
+	name      string                                                // target name, reported in auth errors
+	login     string                                                // selector identifying the targets login page
+	loginURLs []*url.URL                                            // redirects to these require re-authentication
+	renew     func() (http.CookieJar, error)                        // re-authenticates, returning the renewed cookie jar
+	header    func() (name string, value string, expires time.Time) // header credentials, optional
+	mu        sync.Mutex                                            // renewals are made one at a time
+}
+
+// newSession wraps transport with session renewal for c, login URLs that fail to parse are ignored
+func newSession(transport http.RoundTripper, c *client) *session {
+
+	s := &session{
+		transport: transport,
+		name:      c.name,
+		login:     c.parser.login["attr_login"],
+		renew:     c.renew,
+		header:    c.header,
+	}
+	for _, loginURL := range c.loginURLs {
+		if parsed, err := url.Parse(loginURL); err == nil && loginURL != "" {
+			s.loginURLs = append(s.loginURLs, parsed)
+		}
+	}
+
+	return s
+
+}
+
+// RoundTrip implements http.RoundTripper
+func (s *session) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	authorized, err := s.authorize(req)
+	if err != nil {
+		return nil, &AuthError{Target: s.name, Err: err}
+	}
+
+	resp, err := s.transport.RoundTrip(authorized)
+	if err != nil {
+		return resp, err
+	}
+	if expired, err := s.expired(resp); err != nil || !expired {
+		return resp, err
+	}
+	resp.Body.Close()
+
+	helpers.Logger.Warnf("Session for target: %s has expired requesting %s, re-authenticating", s.name, req.URL)
+
+	s.mu.Lock()
+	jar, err := s.renew()
+	s.mu.Unlock()
+	if err != nil {
+		return nil, &AuthError{Target: s.name, Err: err}
+	}
+
+	retry, err := s.retry(req, jar)
+	if err != nil {
+		return nil, &AuthError{Target: s.name, Err: err}
+	}
+	if retry, err = s.authorize(retry); err != nil {
+		return nil, &AuthError{Target: s.name, Err: err}
+	}
+	if resp, err = s.transport.RoundTrip(retry); err != nil {
+		return resp, err
+	}
+	expired, err := s.expired(resp)
+	if err != nil || !expired {
+		return resp, err
+	}
+	resp.Body.Close()
+
+	return nil, &AuthError{Target: s.name, Err: fmt.Errorf("session expired again after re-authenticating, requesting %s", req.URL)}
+
+}
+
+// authorize returns a copy of req with the sessions header credentials set, renewing
+// them first where they are about to expire. Requests are unchanged without a header.
+func (s *session) authorize(req *http.Request) (*http.Request, error) {
+
+	if s.header == nil {
+		return req, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name, value, expires := s.header()
+	if !expires.IsZero() && time.Now().Add(tokenExpiryMargin).After(expires) {
+		helpers.Logger.Infof("Token for target: %s expires %s, re-authenticating", s.name, expires.Format(time.RFC3339))
+		if _, err := s.renew(); err != nil {
+			return nil, err
+		}
+		name, value, _ = s.header()
+	}
+	if name == "" {
+		return req, nil
+	}
+
+	authorized := req.Clone(req.Context())
+	authorized.Header.Set(name, value)
+
+	return authorized, nil
+
+}
+
+// expired establishes whether resp indicates the session is no longer authenticated,
+// html bodies are read to check for the login page and replaced for the caller
+func (s *session) expired(resp *http.Response) (bool, error) {
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return true, nil
+	}
+
+	if location, err := resp.Location(); err == nil {
+		for _, loginURL := range s.loginURLs {
+			if strings.EqualFold(location.Host, loginURL.Host) && location.Path == loginURL.Path {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	if s.login == "" || !strings.Contains(resp.Header.Get("Content-Type"), "html") {
+		return false, nil
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return false, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return false, nil
+	}
+
+	return doc.Find(s.login).Length() > 0, nil
+
+}
+
+// retry copies req for replaying with the cookies held by jar
+func (s *session) retry(req *http.Request, jar http.CookieJar) (*http.Request, error) {
+
+	retry := req.Clone(req.Context())
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return nil, errors.New("request body cannot be replayed after re-authenticating")
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		retry.Body = body
+	}
+
+	retry.Header.Del("Cookie")
+	if jar != nil {
+		for _, cookie := range jar.Cookies(req.URL) {
+			retry.AddCookie(cookie)
+		}
+	}
+
+	return retry, nil
+
+}
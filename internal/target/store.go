@@ -0,0 +1,304 @@
+package target
+
+import (
+	"brubot/internal/helpers"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultSessionKeyEnv holds the key saved sessions are encrypted with, unless configured otherwise
+const defaultSessionKeyEnv = "BRUBOT_SESSION_KEY"
+
+// defaultSessionMaxAge limits how long a saved session is reused, unless configured otherwise
+const defaultSessionMaxAge = 24 * time.Hour
+
+// sessionStore persists a targets session (cookies and token) between runs, encrypted
+// using AES-GCM with a 256 bit key held (base64 encoded) by an environment variable
+type sessionStore struct {
+	path   string        // Session file for the target (account)
+	key    []byte        // AES-256 key, decoded from the environment variables value
+	name   string        // Target name, binds the saved session to its account
+	maxAge time.Duration // Saved sessions older than this are not reused, zero for no limit
+}
+
+// savedSession is the persisted session, cookies are held with the attributes the target set them with
+type savedSession struct {
+	Saved   time.Time     `json:"saved"`
+	Cookies []savedCookie `json:"cookies"`
+	Token   string        `json:"token,omitempty"`
+	Expires time.Time     `json:"expires,omitempty"`
+}
+
+// savedCookie is a cookie as set by the target along with the URL it was set by
+type savedCookie struct {
+	URL    string       `json:"url"`
+	Cookie *http.Cookie `json:"cookie"`
+}
+
+// sessionJar is a cookie jar recording every cookie the target sets (Set-Cookie) with its
+// attributes, a cookiejar.Jar only returns cookie names and values so cannot be saved as is
+type sessionJar struct {
+	http.CookieJar
+	mu      sync.Mutex
+	cookies map[string]savedCookie // Keyed by domain, path and name
+}
+
+// newSessionJar returns an empty recording cookie jar
+func newSessionJar() *sessionJar {
+
+	jar, _ := cookiejar.New(nil)
+
+	return &sessionJar{CookieJar: jar, cookies: make(map[string]savedCookie)}
+
+}
+
+// SetCookies implements http.CookieJar, recording cookies set for u. Deleted and
+// expired cookies are forgotten, a max age is recorded as its expiry.
+func (j *sessionJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+
+	j.CookieJar.SetCookies(u, cookies)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, cookie := range cookies {
+
+		saved := *cookie
+		saved.Raw = ""
+		saved.Unparsed = nil
+		if saved.MaxAge > 0 {
+			saved.Expires = time.Now().Add(time.Duration(saved.MaxAge) * time.Second)
+			saved.MaxAge = 0
+		}
+
+		domain := saved.Domain
+		if domain == "" {
+			domain = u.Hostname()
+		}
+		key := strings.Join([]string{strings.TrimPrefix(strings.ToLower(domain), "."), saved.Path, saved.Name}, ";")
+
+		if saved.MaxAge < 0 || (!saved.Expires.IsZero() && saved.Expires.Before(time.Now())) {
+			delete(j.cookies, key)
+			continue
+		}
+		j.cookies[key] = savedCookie{URL: u.String(), Cookie: &saved}
+
+	}
+
+}
+
+// saved returns every recorded cookie which has not expired
+func (j *sessionJar) saved() []savedCookie {
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	cookies := make([]savedCookie, 0, len(j.cookies))
+	for _, cookie := range j.cookies {
+		if cookie.Cookie.Expires.IsZero() || cookie.Cookie.Expires.After(time.Now()) {
+			cookies = append(cookies, cookie)
+		}
+	}
+	sort.Slice(cookies, func(i, j int) bool {
+		return cookies[i].URL+cookies[i].Cookie.Name < cookies[j].URL+cookies[j].Cookie.Name
+	})
+
+	return cookies
+
+}
+
+// newSessionStore returns the store for target name within dir, or nil where sessions are
+// not persisted (no dir configured). The key is read from keyEnv (BRUBOT_SESSION_KEY by default)
+// and must be 32 random bytes, base64 encoded (i.e. openssl rand -base64 32). Saved sessions are
+// reused for maxAgeHours (24 by default), negative for no limit.
+func newSessionStore(dir string, keyEnv string, maxAgeHours int, name string) (*sessionStore, error) {
+
+	if dir == "" {
+		return nil, nil
+	}
+	if keyEnv == "" {
+		keyEnv = defaultSessionKeyEnv
+	}
+
+	secret := os.Getenv(keyEnv)
+	if secret == "" {
+		return nil, fmt.Errorf("session store requires a key within environment variable %s", keyEnv)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(secret))
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("session store key within environment variable %s must be 32 bytes base64 encoded "+
+			"(i.e. openssl rand -base64 32)", keyEnv)
+	}
+
+	maxAge := defaultSessionMaxAge
+	switch {
+	case maxAgeHours < 0:
+		maxAge = 0
+	case maxAgeHours > 0:
+		maxAge = time.Duration(maxAgeHours) * time.Hour
+	}
+
+	file := strings.NewReplacer("/", "_", "\\", "_", string(filepath.Separator), "_").Replace(name) + ".session"
+
+	return &sessionStore{
+		path:   filepath.Join(dir, file),
+		key:    key,
+		name:   name,
+		maxAge: maxAge,
+	}, nil
+
+}
+
+// load returns the saved session within a new cookie jar, ok is false where there is
+// no saved session, it has aged out or its token has expired
+func (s *sessionStore) load() (jar *sessionJar, saved savedSession, ok bool, err error) {
+
+	sealed, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, saved, false, nil
+	}
+	if err != nil {
+		return nil, saved, false, err
+	}
+
+	gcm, err := s.cipher()
+	if err != nil {
+		return nil, saved, false, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, saved, false, errors.New("saved session is truncated")
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], []byte(s.name))
+	if err != nil {
+		return nil, saved, false, fmt.Errorf("failed decrypting saved session, has the key changed? %w", err)
+	}
+	if err = json.Unmarshal(plain, &saved); err != nil {
+		return nil, saved, false, err
+	}
+
+	if s.maxAge > 0 && time.Since(saved.Saved) > s.maxAge {
+		return nil, saved, false, nil
+	}
+	if !saved.Expires.IsZero() && time.Now().Add(tokenExpiryMargin).After(saved.Expires) {
+		return nil, saved, false, nil
+	}
+
+	// Cookies are restored with the attributes they were set with, expired cookies are dropped
+	jar = newSessionJar()
+	for _, cookie := range saved.Cookies {
+		u, parseErr := url.Parse(cookie.URL)
+		if parseErr != nil || cookie.Cookie == nil {
+			continue
+		}
+		jar.SetCookies(u, []*http.Cookie{cookie.Cookie})
+	}
+	saved.Cookies = jar.saved()
+
+	return jar, saved, true, nil
+
+}
+
+// save persists the cookies recorded by jar along with a token, replacing any saved session
+func (s *sessionStore) save(jar *sessionJar, token string, expires time.Time) error {
+
+	saved := savedSession{Saved: time.Now(), Token: token, Expires: expires}
+	if jar != nil {
+		saved.Cookies = jar.saved()
+	}
+
+	return s.seal(saved)
+
+}
+
+// seal encrypts and writes saved to the stores session file
+func (s *sessionStore) seal(saved savedSession) error {
+
+	plain, err := json.Marshal(saved)
+	if err != nil {
+		return err
+	}
+
+	gcm, err := s.cipher()
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(s.path, gcm.Seal(nonce, nonce, plain, []byte(s.name)), 0600)
+
+}
+
+// cipher returns the AES-GCM cipher sessions are sealed with
+func (s *sessionStore) cipher() (cipher.AEAD, error) {
+
+	block, err := aes.NewCipher(s.key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+
+}
+
+// restore reuses the targets saved session where there is one, returning the cookie jar to
+// initialise the client with. Saved sessions rejected by the target are renewed, see session.
+func (t *ajaxTarget) restore() (http.CookieJar, bool) {
+
+	// API keys are configured rather than issued, there is no session to reuse
+	if t.store == nil || t.Auth.mode == AuthAPIKey {
+		return nil, false
+	}
+
+	jar, saved, ok, err := t.store.load()
+	if err != nil {
+		helpers.Logger.Warnf("A failure occurred loading saved session for target: %s, authenticating: %v", t.name, err)
+		return nil, false
+	}
+	if !ok || (t.Auth.mode == AuthJSON && saved.Token == "") || (t.Auth.mode == AuthForm && len(saved.Cookies) == 0) {
+		return nil, false
+	}
+
+	t.Auth.cookieJar = jar
+	t.Auth.token.value = saved.Token
+	t.Auth.token.expires = saved.Expires
+	helpers.Logger.Infof("Reusing session for target: %s saved %s", t.name, saved.Saved.Format(time.RFC3339))
+
+	return jar, true
+
+}
+
+// persist saves the targets current session for reuse by later runs, failures are only logged
+func (t *ajaxTarget) persist() {
+
+	if t.store == nil || t.Auth.mode == AuthAPIKey {
+		return
+	}
+
+	if err := t.store.save(t.Auth.cookieJar, t.Auth.token.value, t.Auth.token.expires); err != nil {
+		helpers.Logger.Warnf("A failure occurred saving session for target: %s: %v", t.name, err)
+	}
+
+}
@@ -0,0 +1,217 @@
+package target
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+const testKeyEnv = "BRUBOT_TEST_SESSION_KEY"
+
+func testStore(t *testing.T, dir string, key string, maxAgeHours int, name string) *sessionStore {
+
+	t.Helper()
+
+	t.Setenv(testKeyEnv, key)
+	store, err := newSessionStore(dir, testKeyEnv, maxAgeHours, name)
+	if err != nil {
+		t.Fatalf("newSessionStore: %v", err)
+	}
+
+	return store
+
+}
+
+func testKey(fill byte) string {
+
+	key := make([]byte, 32)
+	for idx := range key {
+		key[idx] = fill
+	}
+
+	return base64.StdEncoding.EncodeToString(key)
+
+}
+
+func testJar(t *testing.T, rawURL string, cookies ...*http.Cookie) *sessionJar {
+
+	t.Helper()
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jar := newSessionJar()
+	jar.SetCookies(u, cookies)
+
+	return jar
+
+}
+
+func TestSessionStoreRoundTrip(t *testing.T) {
+
+	dir := t.TempDir()
+	store := testStore(t, dir, testKey(1), 0, "office")
+
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	jar := testJar(t, "https://tips.example.com/login",
+		&http.Cookie{Name: "session", Value: "abc", Domain: "example.com", Path: "/", Secure: true, Expires: expires},
+		&http.Cookie{Name: "stale", Value: "old", Path: "/", Expires: time.Now().Add(-time.Hour)},
+		&http.Cookie{Name: "prefs", Value: "1", Path: "/tips", MaxAge: 3600},
+	)
+
+	if err := store.save(jar, "token", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	loaded, saved, ok, err := store.load()
+	if err != nil || !ok {
+		t.Fatalf("load: ok %v, err %v", ok, err)
+	}
+	if saved.Token != "token" {
+		t.Errorf("token = %q, want %q", saved.Token, "token")
+	}
+	if len(saved.Cookies) != 2 {
+		t.Fatalf("cookies = %d, want 2 (expired cookie dropped)", len(saved.Cookies))
+	}
+	for _, cookie := range saved.Cookies {
+		switch cookie.Cookie.Name {
+		case "session":
+			if cookie.Cookie.Domain != "example.com" || !cookie.Cookie.Secure || !cookie.Cookie.Expires.Equal(expires) {
+				t.Errorf("session cookie attributes not restored: %+v", cookie.Cookie)
+			}
+		case "prefs":
+			if cookie.Cookie.Path != "/tips" || cookie.Cookie.Expires.IsZero() || cookie.Cookie.MaxAge != 0 {
+				t.Errorf("prefs cookie max age not recorded as expiry: %+v", cookie.Cookie)
+			}
+		default:
+			t.Errorf("unexpected cookie restored: %s", cookie.Cookie.Name)
+		}
+	}
+
+	// Restored cookies are only sent where the target set them
+	tests := []struct {
+		url  string
+		want int
+	}{
+		{"https://www.example.com/", 1},
+		{"https://tips.example.com/tips/round", 2},
+		{"http://tips.example.com/tips/round", 1},
+		{"https://other.example.org/", 0},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		if got := len(loaded.Cookies(u)); got != tt.want {
+			t.Errorf("cookies for %s = %d, want %d", tt.url, got, tt.want)
+		}
+	}
+
+}
+
+func TestSessionStoreRejects(t *testing.T) {
+
+	dir := t.TempDir()
+	jar := testJar(t, "https://tips.example.com/", &http.Cookie{Name: "session", Value: "abc"})
+
+	if err := testStore(t, dir, testKey(1), 0, "office").save(jar, "", time.Time{}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	if _, _, ok, err := testStore(t, dir, testKey(2), 0, "office").load(); err == nil || ok {
+		t.Errorf("load with wrong key: ok %v, err %v, want an error", ok, err)
+	}
+
+	// The account name is bound as associated data, another accounts session cannot be swapped in
+	other := testStore(t, dir, testKey(1), 0, "family")
+	sealed, err := ioutil.ReadFile(testStore(t, dir, testKey(1), 0, "office").path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(other.path, sealed, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok, err := other.load(); err == nil || ok {
+		t.Errorf("load with wrong account: ok %v, err %v, want an error", ok, err)
+	}
+
+}
+
+func TestSessionStoreExpiry(t *testing.T) {
+
+	jar := testJar(t, "https://tips.example.com/", &http.Cookie{Name: "session", Value: "abc"})
+
+	tests := []struct {
+		name        string
+		maxAgeHours int
+		saved       time.Duration // Age of the saved session
+		expires     time.Duration // Token expiry relative to now, 0 for none
+		want        bool
+	}{
+		{"default max age, fresh", 0, time.Hour, 0, true},
+		{"default max age, aged out", 0, 25 * time.Hour, 0, false},
+		{"configured max age, aged out", 2, 3 * time.Hour, 0, false},
+		{"no limit", -1, 24 * 365 * time.Hour, 0, true},
+		{"token valid", 0, 0, time.Hour, true},
+		{"token within expiry margin", 0, 0, tokenExpiryMargin / 2, false},
+		{"token expired", 0, 0, -time.Hour, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			store := testStore(t, t.TempDir(), testKey(1), tt.maxAgeHours, "office")
+
+			var expires time.Time
+			if tt.expires != 0 {
+				expires = time.Now().Add(tt.expires)
+			}
+			if err := store.save(jar, "token", expires); err != nil {
+				t.Fatalf("save: %v", err)
+			}
+			if tt.saved != 0 {
+				// Age the session by rewriting it with an earlier save time
+				_, saved, _, err := (&sessionStore{path: store.path, key: store.key, name: store.name}).load()
+				if err != nil {
+					t.Fatal(err)
+				}
+				saved.Saved = time.Now().Add(-tt.saved)
+				if err = store.seal(saved); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if _, _, ok, err := store.load(); err != nil || ok != tt.want {
+				t.Errorf("load: ok %v, err %v, want ok %v", ok, err, tt.want)
+			}
+
+		})
+	}
+
+}
+
+func TestNewSessionStoreKey(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		key     string
+		wantErr bool
+	}{
+		{"32 byte key", testKey(1), false},
+		{"passphrase", "correct horse battery staple", true},
+		{"short key", base64.StdEncoding.EncodeToString([]byte("too short")), true},
+		{"missing", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(testKeyEnv, tt.key)
+			if _, err := newSessionStore(t.TempDir(), testKeyEnv, 0, "office"); (err != nil) != tt.wantErr {
+				t.Errorf("newSessionStore: err %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+
+}
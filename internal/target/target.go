@@ -2,68 +2,101 @@ package target
 
 import (
 	"brubot/config"
+	"brubot/internal/archive"
+	"brubot/internal/domain"
+	"brubot/internal/teams"
+	"errors"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
 )
 
-// Target is everything required to submit a prediction
-type Target struct {
-	Round         Round         // Round ID, fixtures and predictions for a specific found
-	PreviousRound PreviousRound // Round ID and results for the previous round of fixtures
-	Auth          auth          // Client authentication cookie
-	Client        client        // Colly client instance
+func init() {
+	Register(KindAjax, newAjaxTarget)
 }
 
-// Round contains all fixtures and associated prediction per fixture
-type Round struct {
-	id          int        // ID for a current round, determined by date
-	Fixtures    []fixture  // The fixutes (matches) within a round/round ID
-	Predictions prediction // Predictions associated to each fixture
-}
+// KindAjax is the original target, a site authenticated by form post with fixtures and results
+// scraped from html and predictions submitted one fixture at a time through an AJAX-style endpoint.
+// It is used where a targets kind is not set.
+const KindAjax = "ajax"
 
-// PreviousRound contains fixture results for the most recent completed round
-type PreviousRound struct {
-	id      int      // Will generally be Round.id - 1
-	Results []result // Previous rounds fixtures with match outcomes
+// ajaxTarget is everything required to submit a prediction
+type ajaxTarget struct {
+	name          string       // Target name, see config.TargetConfig
+	Round         Round        // Round ID, fixtures and predictions for a specific found
+	PreviousRound domain.Round // Round ID and results for the previous round of fixtures
+	Auth          auth         // Client authentication cookie
+	Client        client       // Colly client instance
+	teams         *teams.Registry
+	driftPolicy   string        // Parser drift policy applied to results, fixture drift always fails
+	verifyRetries int           // Times mismatched submissions are resubmitted after reading back saved tips
+	store         *sessionStore // Persists the session between runs, nil where sessions are not persisted
 }
 
-// Winning team name and margin/point deficit for a fixture prediction
-type prediction struct {
-	teamMargin map[string]int // winner: margin
+// Round contains all fixtures and associated prediction per fixture
+type Round struct {
+	id       int       // ID for a current round, determined by date
+	Fixtures []fixture // The fixutes (matches) within a round/round ID
 }
 
-// Represents all parameters per-fixture
+// Represents all parameters per-fixture, teams are held within the shared domain.Fixture
+// which predictions are matched against
 type fixture struct {
-	token     string // Unique fixture token, extracted from target
-	leftTeam  string // teamA
-	rightTeam string // teamB
-	leftID    int    // Unique identifer for teamA, extracted from target
-	rightID   int    // Unique identifer for teamB, extracted from target
-	winnerID  int    // Set to teamA or teamB identifer based on prediction
-	margin    int    // Point difference for winning team based on prediction
+	domain.Fixture
+	token    string // Unique fixture token, extracted from target
+	leftID   int    // Unique identifer for teamA, extracted from target
+	rightID  int    // Unique identifer for teamB, extracted from target
+	winnerID int    // Set to teamA or teamB identifer based on prediction
+	margin   int    // Point difference for winning team based on prediction
 }
 
-// Result of a completed fixture (similar to fixture but *Different*)
-type result struct {
-	leftTeam  string // teamA
-	rightTeam string // teamB
-	winner    string // Set to teamA or teamB identifer based on fixture results (or 'draw' in a draw)
-	margin    int    // Point difference for winning team based / winning margin
-}
+// newAjaxTarget sets a Target up with global and target specific configuration paramaeters.
+// Scraped team names are resolved to canonical team IDs through env.Teams.
+func newAjaxTarget(targetConfig config.TargetConfig, env Env) (Target, error) {
+
+	var err error
 
-// Init sets a Target up with global and target specific configuration paramaeters.
-func (t *Target) Init(globalConfig config.GlobalConfig, targetConfig config.TargetConfig) {
+	t := &ajaxTarget{name: targetConfig.Name, verifyRetries: targetConfig.Verify.Retries}
+	// Negative retries only report mismatches
+	switch {
+	case t.verifyRetries == 0:
+		t.verifyRetries = defaultVerifyRetries
+	case t.verifyRetries < 0:
+		t.verifyRetries = 0
+	}
+
+	t.teams = env.Teams
+
+	if t.driftPolicy, err = domain.DriftPolicy(targetConfig.Drift); err != nil {
+		return nil, err
+	}
 
 	// Target authentication establishes successful auth, populates a cookiejar with auth
 	// token(s) to set on client for subsequent querying.
 	//
 	// Parameters from config.TargetConfig.Auth passed to internal/target/auth.go -> auth
 	t.Auth = auth{
-		url:            targetConfig.Auth.URL,
-		parameters:     targetConfig.Auth.Parameters,
-		passwordEncode: targetConfig.Auth.PasswordEncode,
-		method:         targetConfig.Auth.Method,
-		errorMsg:       targetConfig.Auth.ErrorMsg,
-		timeout:        targetConfig.Auth.Timeout,
-		headers:        targetConfig.Auth.Headers,
+		url:              targetConfig.Auth.URL,
+		parameters:       targetConfig.Auth.Parameters,
+		preLoginURL:      targetConfig.Auth.PreLogin.URL,
+		preLoginSelector: targetConfig.Auth.PreLogin.Selector,
+		method:           targetConfig.Auth.Method,
+		errorMsg:         targetConfig.Auth.ErrorMsg,
+		timeout:          targetConfig.Auth.Timeout,
+		headers:          targetConfig.Auth.Headers,
+		token: token{
+			path:       targetConfig.Auth.Token.Path,
+			expiryPath: targetConfig.Auth.Token.ExpiryPath,
+			header:     targetConfig.Auth.Token.Header,
+			prefix:     targetConfig.Auth.Token.Prefix,
+		},
+	}
+	if err = t.Auth.authMode(targetConfig.Auth.Mode); err != nil {
+		return nil, err
+	}
+	if t.store, err = newSessionStore(targetConfig.Auth.Session.Dir, targetConfig.Auth.Session.KeyEnv,
+		targetConfig.Auth.Session.MaxAgeHours, targetConfig.Name); err != nil {
+		return nil, err
 	}
 	// Colly client settings for querying target fixtures and setting predictions.
 	//
@@ -84,17 +117,115 @@ func (t *Target) Init(globalConfig config.GlobalConfig, targetConfig config.Targ
 			results:     targetConfig.Client.Parser.Results,
 			predictions: targetConfig.Client.Parser.Predictions,
 		},
+		// Raw responses are archived against the targets name
+		archive: env.Archive,
+		name:    targetConfig.Name,
+		// Responses redirected to any login URL require re-authentication
+		loginURLs: []string{targetConfig.Auth.URL, targetConfig.Auth.PreLogin.URL, targetConfig.Client.URLs["login"]},
 	}
+	t.Client.renew = t.renew
+	t.Client.header = t.Auth.header
 
 	// Globals allow easier parameter setting across multiple http clients
 	//
 	// At present only user agent can be set globally.
 	if targetConfig.UseGlobals {
-		t.Auth.userAgent = globalConfig.UserAgent
-		t.Client.config.userAgent = globalConfig.UserAgent
+		t.Auth.userAgent = env.Global.UserAgent
+		t.Client.config.userAgent = env.Global.UserAgent
 	} else {
 		t.Auth.userAgent = targetConfig.Auth.UserAgent
 		t.Client.config.userAgent = targetConfig.Client.UserAgent
 	}
 
+	return t, nil
+
+}
+
+// Name returns the targets configured name
+func (t *ajaxTarget) Name() string {
+	return t.name
+}
+
+// team resolves a team name scraped from the target to a canonical team ID
+func (t *ajaxTarget) team(name string) (string, error) {
+
+	if t.teams == nil {
+		return "", errors.New("Target has no team registry, has Init been called?")
+	}
+
+	return t.teams.Resolve(teams.TargetScope, name)
+
+}
+
+// tournament returns the tournament for a fixture, scraped is the value of the optional attr_tournament
+// fixture attribute. When the target does not identify a tournament the teams shared tournament is used.
+func (t *ajaxTarget) tournament(scraped string, leftTeam string, rightTeam string) string {
+
+	if tournament := strings.TrimSpace(scraped); tournament != "" {
+		return tournament
+	}
+	if t.teams == nil {
+		return ""
+	}
+
+	return t.teams.Tournament(leftTeam, rightTeam)
+
+}
+
+// Replay initialises the client to serve responses from an archived run rather than the target,
+// in place of Authenticate. Archived responses were captured by an authenticated client so
+// authentication is skipped.
+func (t *ajaxTarget) Replay(replay *archive.Replay) error {
+	return t.offline(replay)
+}
+
+// offline initialises the client to serve every response from transport, neither
+// the cache or robots.txt are used as they are not archived
+func (t *ajaxTarget) offline(transport http.RoundTripper) error {
+
+	t.Client.config.enableCache = false
+	t.Client.config.ignoreRobots = true
+
+	cookieJar, err := cookiejar.New(nil)
+	if err != nil {
+		return err
+	}
+	if err = t.Client.init(cookieJar); err != nil {
+		return err
+	}
+	t.Client.collector.WithTransport(transport)
+
+	return nil
+
+}
+
+// driftError describes a failed health check against the selector held under key
+// within a target page parser
+func (t *ajaxTarget) driftError(page string, parser map[string]string, key string, check string, err error) error {
+	return &domain.ParserDriftError{Page: page, Key: key, Selector: parser[key], Check: check, Err: err}
+}
+
+// checkMatches reports parser drift for the first of keys whose selector matched nothing
+func (t *ajaxTarget) checkMatches(page string, parser map[string]string, counts map[string]int, keys ...string) error {
+
+	for _, key := range keys {
+		if counts[key] == 0 {
+			return t.driftError(page, parser, key, domain.CheckMatch, nil)
+		}
+	}
+
+	return nil
+
+}
+
+// fixtures returns the fixtures retrieved for the current round
+func (t *ajaxTarget) fixtures() []domain.Fixture {
+
+	fixtures := make([]domain.Fixture, len(t.Round.Fixtures))
+	for idx := range t.Round.Fixtures {
+		fixtures[idx] = t.Round.Fixtures[idx].Fixture
+	}
+
+	return fixtures
+
 }
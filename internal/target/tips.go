@@ -0,0 +1,114 @@
+package target
+
+import (
+	"brubot/internal/domain"
+	"brubot/internal/helpers"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// CurrentTips re-reads the fixtures page for the current round, returning the predictions
+// currently held by the target. Tips are read from optional fixture attributes set within
+// target.client.parser.fixtures:
+//
+//	attr_t_tipwinnerid: attribute holding the tipped team ID, 0 for a draw, empty when not tipped
+//	attr_t_tipmargin:   attribute holding the tipped margin
+//
+// Fixtures are matched to those retrieved by Fixtures using their token.
+func (t *ajaxTarget) CurrentTips() ([]domain.Prediction, error) {
+
+	var err error
+	var tips []domain.Prediction
+
+	if t.Client.parser.fixtures["attr_t_tipwinnerid"] == "" || t.Client.parser.fixtures["attr_t_tipmargin"] == "" {
+		return nil, ErrCurrentTipsUnsupported
+	}
+
+	// Fixtures by token, tips are only reported for known fixtures
+	fixtures := make(map[string]*fixture, len(t.Round.Fixtures))
+	for idx := range t.Round.Fixtures {
+		fixtures[t.Round.Fixtures[idx].token] = &t.Round.Fixtures[idx]
+	}
+
+	collector := t.Client.scraper()
+	t.Client.round = t.Round.id
+
+	collector.OnHTML(t.Client.parser.fixtures["attr_onhtml"], func(e *colly.HTMLElement) {
+
+		e.ForEach(t.Client.parser.fixtures["attr_fixture"], func(_ int, cl *colly.HTMLElement) {
+
+			f, ok := fixtures[cl.Attr(t.Client.parser.fixtures["attr_token"])]
+			if !ok {
+				return
+			}
+
+			tip, tipErr := f.tip(
+				strings.TrimSpace(cl.Attr(t.Client.parser.fixtures["attr_t_tipwinnerid"])),
+				strings.TrimSpace(cl.Attr(t.Client.parser.fixtures["attr_t_tipmargin"])),
+			)
+			if tipErr != nil {
+				err = tipErr
+				return
+			}
+			if tip != nil {
+				tips = append(tips, *tip)
+			}
+
+		})
+
+	})
+
+	// If the login attribute is detected in the response body, authentication has somehow failed
+	collector.OnHTML(t.Client.parser.login["attr_login"], func(e *colly.HTMLElement) {
+		err = &AuthError{Target: t.name, Err: errors.New("An error occurred during current tips retrieval, client is not authenticated")}
+	})
+
+	// Client error has occurred attempting .Visit
+	collector.OnError(func(r *colly.Response, resError error) {
+		err = fmt.Errorf("An error occurred during current tips retrieval, client response %+v URL %s error %w", r, r.Request.URL, resError)
+	})
+
+	collector.Visit(fmt.Sprint(t.Client.config.urls["fixtures"], t.Round.id))
+
+	helpers.Logger.Debugf("Current tips retrieved for round: %d, tips: %d of %d fixtures", t.Round.id, len(tips), len(t.Round.Fixtures))
+
+	return tips, err
+
+}
+
+// tip converts a fixtures scraped tip attributes to a prediction, returning nil when untipped
+func (f *fixture) tip(winnerID string, margin string) (*domain.Prediction, error) {
+
+	if winnerID == "" || winnerID == "-1" {
+		return nil, nil
+	}
+
+	id, err := strconv.Atoi(winnerID)
+	if err != nil {
+		return nil, fmt.Errorf("fixture %s has an invalid tipped team ID: %q", f.Fixture, winnerID)
+	}
+	tipMargin, err := strconv.Atoi(margin)
+	if err != nil && id != 0 {
+		return nil, fmt.Errorf("fixture %s has an invalid tipped margin: %q", f.Fixture, margin)
+	}
+
+	tip := domain.Prediction{Fixture: f.Fixture, Margin: tipMargin}
+	switch id {
+	case 0:
+		tip.Winner = domain.Draw
+		tip.Margin = 0
+	case f.leftID:
+		tip.Winner = f.LeftTeam
+	case f.rightID:
+		tip.Winner = f.RightTeam
+	default:
+		return nil, fmt.Errorf("fixture %s has a tipped team ID matching neither team: %d", f.Fixture, id)
+	}
+
+	return &tip, nil
+
+}
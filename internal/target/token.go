@@ -0,0 +1,167 @@
+package target
+
+import (
+	"brubot/internal/helpers"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Auth modes, selected by config.TargetConfig.Auth.Mode
+const (
+	AuthForm   = "form"   // Credentials are posted as a form, the session is held by cookie (default)
+	AuthJSON   = "json"   // Credentials are posted as JSON, the returned token is sent as a header
+	AuthAPIKey = "apikey" // A static API key (auth.parameters.key) is sent as a header
+)
+
+// tokenExpiryMargin renews tokens ahead of their expiry, allowing for clock skew and slow requests
+const tokenExpiryMargin = 30 * time.Second
+
+// token holds the header credentials sent with every request for token based auth modes
+type token struct {
+	path       string    // JSON path to the token within the auth response (json)
+	expiryPath string    // JSON path to the tokens lifetime in seconds within the auth response, optional (json)
+	header     string    // Header carrying the token
+	prefix     string    // Prepended to the token, i.e. "Bearer "
+	value      string    // Token returned on successful auth
+	expires    time.Time // Zero where the tokens expiry is unknown, expired tokens are only renewed on a 401
+}
+
+// authMode validates mode, setting token header defaults for token based modes
+func (a *auth) authMode(mode string) error {
+
+	switch mode {
+	case "", AuthForm:
+		a.mode = AuthForm
+		return nil
+	case AuthJSON:
+		a.mode = AuthJSON
+		if a.token.path == "" {
+			return errors.New("json auth requires auth.token.path")
+		}
+		if a.token.header == "" {
+			a.token.header = "Authorization"
+			if a.token.prefix == "" {
+				a.token.prefix = "Bearer "
+			}
+		}
+	case AuthAPIKey:
+		a.mode = AuthAPIKey
+		if a.token.header == "" {
+			a.token.header = "X-API-Key"
+		}
+	default:
+		return fmt.Errorf("unknown auth mode: %q", mode)
+	}
+
+	return nil
+
+}
+
+// tokenLogin posts auth.parameters as JSON to the target authentication url,
+// extracting the token (and its lifetime) from the response
+func (a *auth) tokenLogin(httpClient *http.Client) error {
+
+	payload, err := json.Marshal(a.parameters)
+	if err != nil {
+		return err
+	}
+
+	respBodyBytes, err := a.send(httpClient, "application/json", string(payload))
+	if err != nil {
+		return err
+	}
+
+	// parse response body for auth failure message and react accordingly
+	if a.errorMsg != "" && strings.Contains(string(respBodyBytes), a.errorMsg) {
+		return errors.New("Invalid credentials when authenticating to target")
+	}
+
+	var body interface{}
+	if err = json.Unmarshal(respBodyBytes, &body); err != nil {
+		return fmt.Errorf("failed decoding auth response: %w", err)
+	}
+
+	value, err := helpers.JSONPathString(body, a.token.path)
+	if err != nil {
+		return fmt.Errorf("failed extracting token from auth response: %w", err)
+	}
+	if value == "" {
+		return errors.New("An empty token was received when authenticating to target")
+	}
+
+	a.token.value = value
+	a.token.expires = time.Time{}
+
+	if a.token.expiryPath != "" {
+		lifetime, err := helpers.JSONPathString(body, a.token.expiryPath)
+		if err != nil {
+			return fmt.Errorf("failed extracting token expiry from auth response: %w", err)
+		}
+		seconds, err := strconv.ParseFloat(lifetime, 64)
+		if err != nil {
+			return fmt.Errorf("invalid token expiry within auth response: %q", lifetime)
+		}
+		a.token.expires = time.Now().Add(time.Duration(seconds * float64(time.Second)))
+	} else {
+		a.token.expires = jwtExpiry(value)
+	}
+
+	return nil
+
+}
+
+// apiKey sets the static API key from auth.parameters as the token
+func (a *auth) apiKey() error {
+
+	if a.parameters["key"] == "" {
+		return errors.New("apikey auth requires auth.parameters.key")
+	}
+	a.token.value = a.parameters["key"]
+	a.token.expires = time.Time{}
+
+	return nil
+
+}
+
+// header returns the header credentials to set on every request and when they expire,
+// name is empty for form (cookie) auth
+func (a *auth) header() (name string, value string, expires time.Time) {
+
+	if a.mode == AuthForm || a.token.value == "" {
+		return "", "", time.Time{}
+	}
+
+	return a.token.header, a.token.prefix + a.token.value, a.token.expires
+
+}
+
+// jwtExpiry returns the exp claim of a JWT, or the zero time where value is not a JWT
+// or has no expiry. The token is not verified, its expiry is only used to renew it.
+func jwtExpiry(value string) time.Time {
+
+	parts := strings.Split(value, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}
+	}
+
+	var claims struct {
+		Exp float64 `json:"exp"`
+	}
+	if err = json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(int64(claims.Exp), 0)
+
+}
@@ -0,0 +1,121 @@
+package target
+
+import (
+	"brubot/internal/domain"
+	"brubot/internal/helpers"
+	"fmt"
+)
+
+// Submission statuses, once saved tips have been read back
+const (
+	Confirmed  = "confirmed"  // The saved tip matches the submission
+	Mismatched = "mismatch"   // The saved tip differs from the submission (or is missing)
+	Unverified = "unverified" // The target is unable to report saved tips
+)
+
+// defaultVerifyRetries is the number of times mismatched submissions are resubmitted
+const defaultVerifyRetries = 2
+
+// Confirmation reports whether a submission was saved by the target
+type Confirmation struct {
+	Submission
+	Saved    *domain.Prediction // Tip read back from the target, nil when missing or unverified
+	Status   string             // Confirmed, Mismatched or Unverified
+	Attempts int                // Number of times the submission was sent
+}
+
+// VerificationError is returned when submissions do not match the tips saved
+// by the target once every retry has been exhausted
+type VerificationError struct {
+	Mismatched []Confirmation
+}
+
+func (e *VerificationError) Error() string {
+
+	msg := fmt.Sprintf("%d submission(s) do not match saved tips:", len(e.Mismatched))
+	for _, c := range e.Mismatched {
+		saved := "none"
+		if c.Saved != nil {
+			saved = fmt.Sprintf("%s %d", c.Saved.Winner, c.Saved.Margin)
+		}
+		msg += fmt.Sprintf(" %s (submitted %s %d, saved %s)", c.Prediction.Fixture, c.Prediction.Winner, c.Prediction.Margin, saved)
+	}
+
+	return msg
+
+}
+
+// verify reads back saved tips with read once submissions have been sent, resubmitting
+// mismatched submissions with resubmit up to retries times
+func verify(submissions []Submission, read func() ([]domain.Prediction, error),
+	resubmit func([]Submission) error, retries int) ([]Confirmation, error) {
+
+	confirmations := make([]Confirmation, len(submissions))
+	for idx := range submissions {
+		confirmations[idx] = Confirmation{Submission: submissions[idx], Attempts: 1}
+	}
+
+	for attempt := 0; ; attempt++ {
+
+		saved, err := read()
+		if err == ErrCurrentTipsUnsupported {
+			for idx := range confirmations {
+				confirmations[idx].Status = Unverified
+			}
+			return confirmations, nil
+		}
+		if err != nil {
+			return confirmations, fmt.Errorf("failed reading back saved tips: %w", err)
+		}
+
+		var retry []Submission
+		var mismatched []int
+		for idx := range confirmations {
+			confirmations[idx].confirm(saved)
+			if confirmations[idx].Status == Mismatched {
+				retry = append(retry, confirmations[idx].Submission)
+				mismatched = append(mismatched, idx)
+			}
+		}
+
+		if len(retry) == 0 {
+			return confirmations, nil
+		}
+		if attempt >= retries {
+			verifyErr := &VerificationError{}
+			for _, idx := range mismatched {
+				verifyErr.Mismatched = append(verifyErr.Mismatched, confirmations[idx])
+			}
+			return confirmations, verifyErr
+		}
+
+		helpers.Logger.Warnf("Resubmitting %d mismatched submission(s), retry %d of %d", len(retry), attempt+1, retries)
+		if err = resubmit(retry); err != nil {
+			return confirmations, err
+		}
+		for _, idx := range mismatched {
+			confirmations[idx].Attempts++
+		}
+
+	}
+
+}
+
+// confirm compares a submission against saved tips, setting Saved and Status
+func (c *Confirmation) confirm(saved []domain.Prediction) {
+
+	c.Saved = nil
+	c.Status = Mismatched
+
+	for idx := range saved {
+		if !saved[idx].Matches(c.Prediction.Fixture) {
+			continue
+		}
+		c.Saved = &saved[idx]
+		if saved[idx].Winner == c.Prediction.Winner && saved[idx].Margin == c.Prediction.Margin {
+			c.Status = Confirmed
+		}
+		return
+	}
+
+}
@@ -0,0 +1,194 @@
+package target
+
+import (
+	"brubot/internal/domain"
+	"errors"
+	"testing"
+)
+
+// fakeTips holds the tips saved by a fake target, submissions are only saved once
+// the target has ignored its first ignore sends
+type fakeTips struct {
+	saved  []domain.Prediction
+	ignore int
+	reads  int
+	sends  int
+}
+
+func (f *fakeTips) read() ([]domain.Prediction, error) {
+	f.reads++
+	return append([]domain.Prediction(nil), f.saved...), nil
+}
+
+func (f *fakeTips) send(submissions []Submission) error {
+
+	f.sends++
+	if f.sends <= f.ignore {
+		return nil
+	}
+
+	for _, s := range submissions {
+		replaced := false
+		for idx := range f.saved {
+			if f.saved[idx].Matches(s.Prediction.Fixture) {
+				f.saved[idx], replaced = s.Prediction, true
+			}
+		}
+		if !replaced {
+			f.saved = append(f.saved, s.Prediction)
+		}
+	}
+
+	return nil
+
+}
+
+func TestVerify(t *testing.T) {
+
+	bluesChiefs := domain.Fixture{LeftTeam: "blues", RightTeam: "chiefs"}
+	redsBrumbies := domain.Fixture{LeftTeam: "reds", RightTeam: "brumbies"}
+	submissions := []Submission{
+		{Prediction: domain.NewPrediction(bluesChiefs, 7)},
+		{Prediction: domain.NewPrediction(redsBrumbies, -3)},
+	}
+
+	tests := []struct {
+		name         string
+		saved        []domain.Prediction // Tips saved by the target before verifying
+		ignore       int                 // Resubmissions ignored by the target
+		retries      int
+		wantStatus   []string
+		wantAttempts []int
+		wantReads    int
+		wantErr      bool
+	}{
+		{
+			name:         "confirmed on first read",
+			saved:        []domain.Prediction{submissions[1].Prediction, submissions[0].Prediction},
+			retries:      2,
+			wantStatus:   []string{Confirmed, Confirmed},
+			wantAttempts: []int{1, 1},
+			wantReads:    1,
+		},
+		{
+			name:         "reversed fixture confirmed",
+			saved:        []domain.Prediction{domain.NewPrediction(domain.Fixture{LeftTeam: "chiefs", RightTeam: "blues"}, -7), submissions[1].Prediction},
+			retries:      2,
+			wantStatus:   []string{Confirmed, Confirmed},
+			wantAttempts: []int{1, 1},
+			wantReads:    1,
+		},
+		{
+			name:         "mismatch fixed on retry",
+			saved:        []domain.Prediction{domain.NewPrediction(bluesChiefs, 3), submissions[1].Prediction},
+			retries:      2,
+			wantStatus:   []string{Confirmed, Confirmed},
+			wantAttempts: []int{2, 1},
+			wantReads:    2,
+		},
+		{
+			name:         "missing fixed on second retry",
+			saved:        []domain.Prediction{submissions[0].Prediction},
+			ignore:       1,
+			retries:      2,
+			wantStatus:   []string{Confirmed, Confirmed},
+			wantAttempts: []int{1, 3},
+			wantReads:    3,
+		},
+		{
+			name:         "retries exhausted",
+			saved:        []domain.Prediction{domain.NewPrediction(bluesChiefs, 3)},
+			ignore:       10,
+			retries:      2,
+			wantStatus:   []string{Mismatched, Mismatched},
+			wantAttempts: []int{3, 3},
+			wantReads:    3,
+			wantErr:      true,
+		},
+		{
+			name:         "no retries only report",
+			saved:        []domain.Prediction{submissions[0].Prediction},
+			retries:      0,
+			wantStatus:   []string{Confirmed, Mismatched},
+			wantAttempts: []int{1, 1},
+			wantReads:    1,
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			target := &fakeTips{saved: tt.saved, ignore: tt.ignore}
+			confirmations, err := verify(submissions, target.read, target.send, tt.retries)
+
+			var verifyErr *VerificationError
+			if tt.wantErr != errors.As(err, &verifyErr) {
+				t.Fatalf("verify() err = %v, want VerificationError %v", err, tt.wantErr)
+			}
+			if tt.wantErr && len(verifyErr.Mismatched) != countStatus(tt.wantStatus, Mismatched) {
+				t.Errorf("VerificationError reports %d mismatches, want %d", len(verifyErr.Mismatched), countStatus(tt.wantStatus, Mismatched))
+			}
+			if target.reads != tt.wantReads {
+				t.Errorf("saved tips read %d times, want %d", target.reads, tt.wantReads)
+			}
+			for idx, c := range confirmations {
+				if c.Status != tt.wantStatus[idx] || c.Attempts != tt.wantAttempts[idx] {
+					t.Errorf("%s: status %s after %d attempts, want %s after %d", c.Prediction.Fixture, c.Status, c.Attempts, tt.wantStatus[idx], tt.wantAttempts[idx])
+				}
+				if c.Status == Confirmed && (c.Saved == nil || c.Saved.Winner != c.Prediction.Winner || c.Saved.Margin != c.Prediction.Margin) {
+					t.Errorf("%s: confirmed without the saved tip", c.Prediction.Fixture)
+				}
+			}
+
+		})
+	}
+
+}
+
+func TestVerifyUnsupported(t *testing.T) {
+
+	submissions := []Submission{{Prediction: domain.NewPrediction(domain.Fixture{LeftTeam: "blues", RightTeam: "chiefs"}, 7)}}
+
+	read := func() ([]domain.Prediction, error) { return nil, ErrCurrentTipsUnsupported }
+	resubmit := func([]Submission) error {
+		t.Error("resubmitted without being able to read back saved tips")
+		return nil
+	}
+
+	confirmations, err := verify(submissions, read, resubmit, 2)
+	if err != nil {
+		t.Fatalf("verify() err = %v, want none", err)
+	}
+	if len(confirmations) != 1 || confirmations[0].Status != Unverified || confirmations[0].Saved != nil {
+		t.Errorf("verify() = %+v, want a single unverified confirmation", confirmations)
+	}
+
+}
+
+func TestVerifyReadFailure(t *testing.T) {
+
+	submissions := []Submission{{Prediction: domain.NewPrediction(domain.Fixture{LeftTeam: "blues", RightTeam: "chiefs"}, 7)}}
+	failure := errors.New("connection reset")
+
+	read := func() ([]domain.Prediction, error) { return nil, failure }
+	resubmit := func([]Submission) error { return nil }
+
+	if _, err := verify(submissions, read, resubmit, 2); !errors.Is(err, failure) {
+		t.Errorf("verify() err = %v, want the read failure", err)
+	}
+
+}
+
+func countStatus(statuses []string, status string) int {
+
+	count := 0
+	for _, s := range statuses {
+		if s == status {
+			count++
+		}
+	}
+
+	return count
+
+}
@@ -0,0 +1,229 @@
+/*
+   Canonical team registry, every team name scraped from sources and the target
+   resolves to a stable team ID through the registry (or fails loudly).
+
+   Names are normalised before matching (case, diacritics/macrons, articles and
+   whitespace), aliases can be global or scoped to a single source or the target.
+*/
+
+package teams
+
+import (
+	"brubot/config"
+	"database/sql"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// TargetScope is the alias scope for names scraped from the target
+const TargetScope = "target"
+
+// Team is a canonical team
+type Team struct {
+	ID         string // Stable team identifier, recorded against predictions and results
+	Name       string // Display name
+	Tournament string // Tournament the team plays in, used where the target does not provide one
+}
+
+// Registry resolves scraped team names to canonical team IDs
+type Registry struct {
+	teams   map[string]Team              // team ID: team
+	aliases map[string]map[string]string // scope: normalised alias: team ID, "" being the global scope
+}
+
+// UnknownTeamError is returned when a name cannot be resolved to a canonical team
+type UnknownTeamError struct {
+	Scope string // Source name or target the name was scraped from
+	Name  string // Name as scraped
+}
+
+func (e *UnknownTeamError) Error() string {
+	return fmt.Sprintf("unknown team: %q (normalised: %q) from %s, add it to global.teams or as an alias", e.Name, Normalise(e.Name), e.Scope)
+}
+
+// New builds a registry from the global.teams config stanza
+func New(globalConfig config.GlobalConfig) (*Registry, error) {
+
+	r := &Registry{
+		teams:   make(map[string]Team),
+		aliases: make(map[string]map[string]string),
+	}
+
+	for _, team := range globalConfig.Teams {
+
+		if err := r.Add(team.ID, team.Name); err != nil {
+			return nil, err
+		}
+		r.teams[team.ID] = Team{ID: team.ID, Name: team.Name, Tournament: team.Tournament}
+		for _, alias := range team.Aliases {
+			if err := r.Alias(team.ID, "", alias); err != nil {
+				return nil, err
+			}
+		}
+		for scope, aliases := range team.Sources {
+			for _, alias := range aliases {
+				if err := r.Alias(team.ID, scope, alias); err != nil {
+					return nil, err
+				}
+			}
+		}
+		for _, alias := range team.Target {
+			if err := r.Alias(team.ID, TargetScope, alias); err != nil {
+				return nil, err
+			}
+		}
+
+	}
+
+	return r, nil
+
+}
+
+// Add registers a canonical team, both its ID and name resolve to the team globally
+func (r *Registry) Add(id string, name string) error {
+
+	if id == "" {
+		return fmt.Errorf("team %q has no id", name)
+	}
+	if _, dup := r.teams[id]; dup {
+		return fmt.Errorf("team id %s is registered twice", id)
+	}
+
+	r.teams[id] = Team{ID: id, Name: name}
+
+	if err := r.Alias(id, "", id); err != nil {
+		return err
+	}
+	if name != "" {
+		return r.Alias(id, "", name)
+	}
+
+	return nil
+
+}
+
+// Alias registers an alternative name for a team, scope limits the alias to names scraped from a
+// single source (by source name) or the target (TargetScope), an empty scope applies everywhere
+func (r *Registry) Alias(id string, scope string, alias string) error {
+
+	if _, ok := r.teams[id]; !ok {
+		return fmt.Errorf("alias %q refers to unknown team id %s", alias, id)
+	}
+
+	// viper lower cases map keys, so scopes are matched case insensitively
+	scope = strings.ToLower(scope)
+	if r.aliases[scope] == nil {
+		r.aliases[scope] = make(map[string]string)
+	}
+
+	key := Normalise(alias)
+	if existing, ok := r.aliases[scope][key]; ok && existing != id {
+		return fmt.Errorf("alias %q (scope %q) is ambiguous between teams %s and %s", alias, scope, existing, id)
+	}
+	r.aliases[scope][key] = id
+
+	return nil
+
+}
+
+// LoadDB adds teams and aliases recorded within the teams and team_aliases tables,
+// an empty team_aliases scope applies to all sources and the target
+func (r *Registry) LoadDB(db *sql.DB) error {
+
+	rows, err := db.Query("SELECT id, name FROM teams")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, name string
+		if err = rows.Scan(&id, &name); err != nil {
+			return err
+		}
+		// Teams may be in both config and the backend
+		if _, ok := r.teams[id]; ok {
+			continue
+		}
+		if err = r.Add(id, name); err != nil {
+			return err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	aliasRows, err := db.Query("SELECT team_id, scope, alias FROM team_aliases")
+	if err != nil {
+		return err
+	}
+	defer aliasRows.Close()
+
+	for aliasRows.Next() {
+		var id, scope, alias string
+		if err = aliasRows.Scan(&id, &scope, &alias); err != nil {
+			return err
+		}
+		if err = r.Alias(id, scope, alias); err != nil {
+			return err
+		}
+	}
+
+	return aliasRows.Err()
+
+}
+
+// Resolve returns the canonical team ID for a name scraped from scope (a source name or TargetScope),
+// aliases scoped to scope take precedence over global aliases. An unresolved name is an *UnknownTeamError.
+func (r *Registry) Resolve(scope string, name string) (string, error) {
+
+	key := Normalise(name)
+
+	if id, ok := r.aliases[strings.ToLower(scope)][key]; ok {
+		return id, nil
+	}
+	if id, ok := r.aliases[""][key]; ok {
+		return id, nil
+	}
+
+	return "", &UnknownTeamError{Scope: scope, Name: name}
+
+}
+
+// Team returns the canonical team for id
+func (r *Registry) Team(id string) (Team, bool) {
+	team, ok := r.teams[id]
+	return team, ok
+}
+
+// Tournament returns the tournament shared by both teams of a fixture,
+// or an empty string when unknown or the teams differ
+func (r *Registry) Tournament(leftTeam string, rightTeam string) string {
+
+	if r.teams[leftTeam].Tournament != r.teams[rightTeam].Tournament {
+		return ""
+	}
+
+	return r.teams[leftTeam].Tournament
+
+}
+
+// Normalise lowers case, strips diacritics (i.e. macrons in Māori names), a leading
+// article and collapses whitespace, so trivially different names compare equal
+func Normalise(name string) string {
+
+	stripped, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), name)
+	if err != nil {
+		stripped = name
+	}
+
+	name = strings.Join(strings.Fields(strings.ToLower(stripped)), " ")
+
+	return strings.TrimPrefix(name, "the ")
+
+}
@@ -0,0 +1,139 @@
+package teams
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalise(t *testing.T) {
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Crusaders", "crusaders"},
+		{"  Moana   Pasifika\t", "moana pasifika"},
+		{"Ngāti Toa", "ngati toa"},  // precomposed macron
+		{"Ngāti Toa", "ngati toa"}, // decomposed macron
+		{"Stade Français", "stade francais"},
+		{"The Blues", "blues"},
+		{"  THE   Chiefs", "chiefs"},
+		{"Theatre Royals", "theatre royals"}, // only the article is dropped
+		{"Blues The", "blues the"},
+		{"The", "the"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Normalise(tt.name); got != tt.want {
+			t.Errorf("Normalise(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+}
+
+func TestResolve(t *testing.T) {
+
+	r := &Registry{teams: make(map[string]Team), aliases: make(map[string]map[string]string)}
+	for _, team := range []struct{ id, name string }{
+		{"blues", "Blues"},
+		{"chiefs", "Chiefs"},
+		{"reds", "Queensland Reds"},
+		{"nsw", "NSW Waratahs"},
+		{"hurricanes", "Hurricanes"},
+	} {
+		if err := r.Add(team.id, team.name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	aliases := []struct{ id, scope, alias string }{
+		{"reds", "", "Reds"},
+		{"chiefs", "", "Gallagher Chiefs"},
+		// One source calls the Waratahs "Reds" (a scraping quirk), only within its own scope
+		{"nsw", "Squiggle", "Reds"},
+		{"nsw", "Squiggle", "Tahs"},
+		{"hurricanes", TargetScope, "Canes"},
+	}
+	for _, a := range aliases {
+		if err := r.Alias(a.id, a.scope, a.alias); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		scope   string
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"VisionAu", "blues", "blues", false},
+		{"VisionAu", "The Blues", "blues", false},
+		{"VisionAu", "Queensland Reds", "reds", false},
+		{"VisionAu", "reds", "reds", false},
+		{"VisionAu", "GALLAGHER  chiefs", "chiefs", false},
+		// Scoped aliases take precedence over global aliases
+		{"Squiggle", "Reds", "nsw", false},
+		{"squiggle", "Reds", "nsw", false}, // scopes are case insensitive
+		{"Squiggle", "Queensland Reds", "reds", false},
+		{"Squiggle", "Tahs", "nsw", false},
+		// Scoped aliases do not apply elsewhere
+		{"VisionAu", "Tahs", "", true},
+		{TargetScope, "Canes", "hurricanes", false},
+		{"VisionAu", "Canes", "", true},
+		{"VisionAu", "Highlanders", "", true},
+		{"VisionAu", "", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := r.Resolve(tt.scope, tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Resolve(%q, %q) err = %v, want error %v", tt.scope, tt.name, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			var unknown *UnknownTeamError
+			if !errors.As(err, &unknown) || unknown.Name != tt.name || unknown.Scope != tt.scope {
+				t.Errorf("Resolve(%q, %q) err = %v, want an UnknownTeamError", tt.scope, tt.name, err)
+			}
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Resolve(%q, %q) = %q, want %q", tt.scope, tt.name, got, tt.want)
+		}
+	}
+
+}
+
+func TestAliasInvalid(t *testing.T) {
+
+	r := &Registry{teams: make(map[string]Team), aliases: make(map[string]map[string]string)}
+	if err := r.Add("blues", "Blues"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Add("chiefs", "Chiefs"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"duplicate id", r.Add("blues", "Auckland Blues")},
+		{"missing id", r.Add("", "Highlanders")},
+		{"unknown team", r.Alias("highlanders", "", "Landers")},
+		// Names equal once normalised cannot refer to different teams within a scope
+		{"ambiguous alias", r.Alias("chiefs", "", "THE blues")},
+	}
+
+	for _, tt := range tests {
+		if tt.err == nil {
+			t.Errorf("%s succeeded, want an error", tt.name)
+		}
+	}
+
+	// The same alias may refer to different teams in different scopes
+	if err := r.Alias("chiefs", "Squiggle", "The Blues"); err != nil {
+		t.Errorf("scoped alias: %v", err)
+	}
+
+}